@@ -0,0 +1,275 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Access control list for MQTT topics, deciding which users can publish and subscribe to which
+// topics. Only applies to clients: messages published by the server itself are always allowed.
+// Can be loaded from a JSON file with LoadACL.
+type ACL struct {
+	// Usernames of the users with each role, by role name.
+	Roles map[string][]string `json:"roles"`
+
+	// Rules in order of precedence. The first rule that applies to a user and matches a topic
+	// decides the user's access to the topic. Access is denied if no rule matches.
+	Rules []ACLRule `json:"rules"`
+}
+
+// A rule in an ACL, granting access to the topics matching a topic filter.
+type ACLRule struct {
+	// MQTT topic filter of the topics the rule applies to, with + and # wildcards. A level of
+	// ClientIDPlaceholder stands for the ID of the client whose access is checked.
+	Filter string `json:"filter"`
+
+	// Access granted to the matching topics.
+	Access Access `json:"access"`
+
+	// Usernames of the users the rule applies to. The rule applies to all users if neither users
+	// nor roles are given.
+	Users []string `json:"users,omitempty"`
+
+	// Roles of the users the rule applies to.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Access to topics granted by an ACL rule.
+type Access string
+
+const (
+	// May subscribe to the topics, but not publish to them.
+	AccessRead Access = "read"
+
+	// May publish to the topics, but not subscribe to them.
+	AccessWrite Access = "write"
+
+	// May subscribe and publish to the topics.
+	AccessReadWrite Access = "readwrite"
+
+	// May neither subscribe nor publish to the topics.
+	AccessNone Access = "none"
+)
+
+// Topic level in an ACL rule's filter that stands for the ID of the client whose access is checked,
+// e.g. for giving each client access to only its own reply topic.
+const ClientIDPlaceholder = "{clientId}"
+
+// Reads an ACL from the JSON file at the given path.
+// Returns error if the file could not be read, or the ACL is invalid.
+func LoadACL(path string) (*ACL, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MQTT ACL: %w", err)
+	}
+
+	var acl ACL
+	err = json.Unmarshal(file, &acl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MQTT ACL %v: %w", path, err)
+	}
+
+	for i, rule := range acl.Rules {
+		err := rule.validate(acl.Roles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %v in MQTT ACL %v: %w", i+1, path, err)
+		}
+	}
+
+	return &acl, nil
+}
+
+// Returns error if the rule has an invalid filter or access, or refers to an unknown role.
+func (rule ACLRule) validate(roles map[string][]string) error {
+	if !validFilter(rule.Filter) {
+		return fmt.Errorf("invalid topic filter '%v'", rule.Filter)
+	}
+
+	switch rule.Access {
+	case AccessRead, AccessWrite, AccessReadWrite, AccessNone:
+	default:
+		return fmt.Errorf("unknown access '%v'", rule.Access)
+	}
+
+	for _, role := range rule.Roles {
+		if _, ok := roles[role]; !ok {
+			return fmt.Errorf("unknown role '%v'", role)
+		}
+	}
+
+	return nil
+}
+
+// Returns whether the given user, connected as the client with the given ID, may publish to the
+// given topic (if write is true), or subscribe to the given topic filter (if write is false). Rules
+// with ClientIDPlaceholder in their filter are skipped if the client ID is empty, or not usable as
+// a topic level. Subscriptions are decided by the first rule whose
+// filter covers the whole subscription filter, but are denied if an earlier rule denying access
+// overlaps the subscription filter, as the broker only checks access when subscribing, and the
+// subscription would then receive the denied topics. Logs denials.
+func (acl *ACL) Allows(user string, clientID string, topic string, write bool) bool {
+	allowed := false
+	for _, rule := range acl.Rules {
+		if !rule.appliesTo(user, acl.Roles) {
+			continue
+		}
+
+		filter, ok := rule.filterFor(clientID)
+		if !ok {
+			continue
+		}
+
+		if filterCovers(filter, topic) {
+			allowed = rule.Access.allows(write)
+			break
+		}
+		if !rule.Access.allows(write) && filtersOverlap(filter, topic) {
+			break
+		}
+	}
+
+	if !allowed {
+		action := "subscribe to"
+		if write {
+			action = "publish to"
+		}
+		log.Printf(
+			"ACL denied user '%v' (client ID %v) to %v topic '%v'\n", user, clientID, action, topic,
+		)
+	}
+
+	return allowed
+}
+
+// Returns whether the rule applies to the given user, with the roles in the given role map.
+func (rule ACLRule) appliesTo(user string, roles map[string][]string) bool {
+	if len(rule.Users) == 0 && len(rule.Roles) == 0 {
+		return true
+	}
+
+	for _, ruleUser := range rule.Users {
+		if ruleUser == user {
+			return true
+		}
+	}
+
+	for _, role := range rule.Roles {
+		for _, roleUser := range roles[role] {
+			if roleUser == user {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns the rule's filter with any ClientIDPlaceholder levels replaced by the given client ID.
+// Returns false if the filter has a placeholder, and the client ID is empty or contains a level
+// separator or wildcard, as it would then not stand for a single topic level.
+func (rule ACLRule) filterFor(clientID string) (filter string, ok bool) {
+	levels := strings.Split(rule.Filter, "/")
+	for i, level := range levels {
+		if level != ClientIDPlaceholder {
+			continue
+		}
+		if clientID == "" || strings.ContainsAny(clientID, "/+#") {
+			return "", false
+		}
+		levels[i] = clientID
+	}
+
+	return strings.Join(levels, "/"), true
+}
+
+// Returns whether the access allows publishing (if write is true) or subscribing.
+func (access Access) allows(write bool) bool {
+	if write {
+		return access == AccessWrite || access == AccessReadWrite
+	}
+	return access == AccessRead || access == AccessReadWrite
+}
+
+// Returns whether the given topic filter is valid: non-empty, with # only as the last level, and
+// wildcards and ClientIDPlaceholder only as whole levels.
+func validFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+		if strings.ContainsAny(level, "{}") && level != ClientIDPlaceholder {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns whether every topic matched by the given topic (a topic name, or a subscription filter
+// with wildcards) is also matched by the given filter.
+func filterCovers(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, filterLevel := range filterLevels {
+		if filterLevel == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+
+		switch topicLevel := topicLevels[i]; filterLevel {
+		case "+":
+			if topicLevel == "#" {
+				return false
+			}
+		default:
+			if topicLevel != filterLevel {
+				return false
+			}
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+// Returns whether any topic is matched by both of the given topic filters (or topic names).
+func filtersOverlap(filter1 string, filter2 string) bool {
+	levels1 := strings.Split(filter1, "/")
+	levels2 := strings.Split(filter2, "/")
+
+	for i := 0; i < len(levels1) && i < len(levels2); i++ {
+		level1, level2 := levels1[i], levels2[i]
+		if level1 == "#" || level2 == "#" {
+			return true
+		}
+		if level1 != "+" && level2 != "+" && level1 != level2 {
+			return false
+		}
+	}
+
+	if len(levels1) == len(levels2) {
+		return true
+	}
+
+	// A trailing # also matches its parent level, so "a/#" matches "a".
+	if len(levels1) == len(levels2)+1 {
+		return levels1[len(levels2)] == "#"
+	}
+	if len(levels2) == len(levels1)+1 {
+		return levels2[len(levels1)] == "#"
+	}
+	return false
+}
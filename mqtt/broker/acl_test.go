@@ -0,0 +1,147 @@
+package broker
+
+import "testing"
+
+func TestFilterCovers(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/b", "a/b/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/+", "a/+", true},
+		{"a/+", "a/#", false},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a/#", true},
+		{"a/#", "a/+/c", true},
+		{"a/b", "a/+", false},
+		{"a/b", "a/#", false},
+		{"#", "a/#", true},
+		{"+/b", "+/b", true},
+		{"+/b", "#", false},
+	}
+
+	for _, test := range tests {
+		if got := filterCovers(test.filter, test.topic); got != test.want {
+			t.Errorf("filterCovers(%q, %q) = %v, want %v", test.filter, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestValidFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"a/b", true},
+		{"a/+/c", true},
+		{"a/#", true},
+		{"a/{clientId}", true},
+		{"", false},
+		{"a/#/c", false},
+		{"a/b+", false},
+		{"a/x{clientId}", false},
+	}
+
+	for _, test := range tests {
+		if got := validFilter(test.filter); got != test.want {
+			t.Errorf("validFilter(%q) = %v, want %v", test.filter, got, test.want)
+		}
+	}
+}
+
+func TestFiltersOverlap(t *testing.T) {
+	tests := []struct {
+		filter1 string
+		filter2 string
+		want    bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/b", "a/#", true},
+		{"a/b", "a/+", true},
+		{"a/b", "+/+/+", false},
+		{"a/b/c", "a/#", true},
+		{"a/#", "a", true},
+		{"a", "a/+", false},
+		{"a/+/c", "a/b/+", true},
+		{"a/+/c", "a/b/d", false},
+		{"#", "x/y", true},
+		{"secret/x", "secret/#", true},
+	}
+
+	for _, test := range tests {
+		got := filtersOverlap(test.filter1, test.filter2)
+		if got != test.want {
+			t.Errorf(
+				"filtersOverlap(%q, %q) = %v, want %v", test.filter1, test.filter2, got, test.want,
+			)
+		}
+		if reversed := filtersOverlap(test.filter2, test.filter1); reversed != got {
+			t.Errorf("filtersOverlap(%q, %q) is not symmetric", test.filter1, test.filter2)
+		}
+	}
+}
+
+func TestACLAllows(t *testing.T) {
+	acl := &ACL{
+		Roles: map[string][]string{"admins": {"alice"}},
+		Rules: []ACLRule{
+			{Filter: "secret/x", Access: AccessNone},
+			{Filter: "admin/#", Access: AccessReadWrite, Roles: []string{"admins"}},
+			{Filter: "admin/#", Access: AccessNone},
+			{Filter: "news/#", Access: AccessRead},
+			{Filter: "replies/{clientId}", Access: AccessRead},
+			{Filter: "replies/#", Access: AccessNone},
+			{Filter: "#", Access: AccessReadWrite},
+		},
+	}
+
+	tests := []struct {
+		user     string
+		clientID string
+		topic    string
+		write    bool
+		want     bool
+	}{
+		{"bob", "c1", "replies/c1", false, true},
+		{"bob", "c1", "replies/c1", true, false},
+		{"bob", "c1", "replies/c2", false, false},
+		{"bob", "c1", "replies/+", false, false},
+		{"bob", "c1", "replies/#", false, false},
+		{"bob", "", "replies/", false, false},
+		{"bob", "c/1", "replies/c/1", false, false},
+		{"bob", "+", "replies/+", false, false},
+		{"bob", "c1", "secret/x", false, false},
+		{"bob", "c1", "secret/x", true, false},
+		{"bob", "c1", "secret/y", false, true},
+		{"bob", "c1", "secret/#", false, false},
+		{"bob", "c1", "secret/+", false, false},
+		{"bob", "c1", "#", false, false},
+		{"bob", "c1", "+/x", false, false},
+		{"bob", "c1", "+/y", false, false}, // Would receive admin/y.
+		{"bob", "c1", "other/+", false, true},
+		{"bob", "c1", "admin/panel", false, false},
+		{"alice", "c1", "admin/panel", false, true},
+		{"alice", "c1", "admin/#", true, true},
+		{"bob", "c1", "news/today", false, true},
+		{"bob", "c1", "news/today", true, false},
+		{"bob", "c1", "news/#", false, true},
+		{"bob", "c1", "other/topic", true, true},
+	}
+
+	for _, test := range tests {
+		got := acl.Allows(test.user, test.clientID, test.topic, test.write)
+		if got != test.want {
+			t.Errorf(
+				"Allows(%q, %q, %q, write: %v) = %v, want %v",
+				test.user, test.clientID, test.topic, test.write, got, test.want,
+			)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Controls which MQTT clients can connect to the broker. Clients authenticate either with a
+// username and password from a credentials file, or with a short-lived token signed with a secret
+// shared with the web server, which issues tokens to the web app's clients.
+// Implements auth.Controller from mochi-co/mqtt.
+type Auth struct {
+	// Bcrypt hashes of the users' passwords, by username.
+	credentials map[string][]byte
+
+	// Secret for verifying the signatures of tokens. Tokens are rejected if empty.
+	tokenSecret []byte
+}
+
+// Claims of the tokens that clients can authenticate with: JSON Web Tokens signed with HMAC-SHA256
+// (HS256).
+type tokenClaims struct {
+	// Username of the client, which must match the username it connects with.
+	Subject string `json:"sub"`
+
+	// Unix timestamp (in seconds) of when the token expires.
+	ExpiresAt int64 `json:"exp"`
+}
+
+// The only header accepted for tokens, as issued by the web server.
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+// Returns an auth controller for the users in the credentials file at the given path, and for
+// tokens signed with the given secret. Either can be empty to disable that method of
+// authentication. The credentials file has a line per user, with the username and the bcrypt hash
+// of the password separated by a colon, as output by `htpasswd -nbB username password`. Blank lines
+// and lines starting with # are ignored. Returns error if the file could not be read, or is
+// malformed.
+func NewAuth(credentialsPath string, tokenSecret string) (*Auth, error) {
+	credentials := make(map[string][]byte)
+	if credentialsPath != "" {
+		var err error
+		credentials, err = readCredentials(credentialsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Auth{credentials: credentials, tokenSecret: []byte(tokenSecret)}, nil
+}
+
+// Reads the username and password hash pairs in the credentials file at the given path.
+// Returns error if the file could not be read, or has a line that is not a valid pair.
+func readCredentials(path string) (map[string][]byte, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MQTT credentials: %w", err)
+	}
+
+	credentials := make(map[string][]byte)
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		username, hash, ok := strings.Cut(line, ":")
+		if !ok || username == "" {
+			return nil, fmt.Errorf(
+				"invalid MQTT credentials %v (line %v): expected username:hash", path, lineNumber,
+			)
+		}
+
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf(
+				"invalid MQTT credentials %v (line %v): %w", path, lineNumber, err,
+			)
+		}
+
+		credentials[username] = []byte(hash)
+	}
+
+	return credentials, nil
+}
+
+// Returns true if the given password is the user's password in the credentials file, or a valid,
+// unexpired token for the user.
+func (controller *Auth) Authenticate(user []byte, password []byte) bool {
+	if hash, ok := controller.credentials[string(user)]; ok {
+		return bcrypt.CompareHashAndPassword(hash, password) == nil
+	}
+
+	return controller.verifyToken(string(user), string(password))
+}
+
+// Returns true, as all authenticated clients may read and write all topics.
+func (controller *Auth) ACL(user []byte, topic string, write bool) bool {
+	return true
+}
+
+// Returns whether the given token is signed with the auth's token secret, is issued to the given
+// user, and has not expired.
+func (controller *Auth) verifyToken(user string, token string) bool {
+	if len(controller.tokenSecret) == 0 {
+		return false
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, controller.tokenSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return false
+	}
+
+	var header tokenHeader
+	if !decodeTokenPart(parts[0], &header) || header.Algorithm != "HS256" {
+		return false
+	}
+
+	var claims tokenClaims
+	if !decodeTokenPart(parts[1], &claims) {
+		return false
+	}
+
+	return claims.Subject == user && time.Now().Unix() < claims.ExpiresAt
+}
+
+// Decodes the given base64url-encoded JSON part of a token into the given target.
+// Returns false if the part is malformed.
+func decodeTokenPart(part string, target any) bool {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return false
+	}
+
+	return json.Unmarshal(data, target) == nil
+}
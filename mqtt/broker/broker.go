@@ -2,40 +2,204 @@
 package broker
 
 import (
-	"embed"
 	"fmt"
 	"log"
-	"os"
+	"net"
 	"strings"
+	"time"
 
+	"github.com/dcs-team4/coffeetalk/tlscert"
 	mqtt "github.com/mochi-co/mqtt/server"
 	"github.com/mochi-co/mqtt/server/events"
 	"github.com/mochi-co/mqtt/server/listeners"
 	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"github.com/mochi-co/mqtt/server/persistence/bolt"
 )
 
-// For production environment: expects tls-cert.pem and tls-key.pem in tls directory.
-//
-//go:embed all:tls
-var tlsFiles embed.FS
+// Optional configuration of the MQTT broker created by New.
+type Option func(*options)
 
-// Creates an MQTT broker configured to listen for WebSocket and TCP connections on the given ports.
-// Returns an error if setup failed.
-func New(socketPort string, tcpPort string) (*mqtt.Server, error) {
+// Configuration set by Options.
+type options struct {
+	// Controls which clients can connect to the broker. Allows all clients if nil.
+	auth auth.Controller
+
+	// Controls which topics clients can publish and subscribe to. Allows all topics if nil.
+	acl *ACL
+
+	// Path to the file where the broker persists its state. Not persisted if empty.
+	persistencePath string
+
+	// Certificate for securing connections with TLS. Connections are not secured if nil.
+	certificate *tlscert.Certificate
+
+	// How often the broker publishes its statistics on the $SYS topics. Uses the mochi-co/mqtt
+	// default if less than a millisecond.
+	statsInterval time.Duration
+}
+
+// Sets the auth controller deciding which clients can connect to the broker, such as an Auth.
+// Without this option, all clients are allowed.
+func WithAuth(controller auth.Controller) Option {
+	return func(options *options) {
+		options.auth = controller
+	}
+}
+
+// Sets the ACL deciding which topics clients can publish and subscribe to, in addition to any
+// topic restrictions of the auth controller. Without this option, all topics are allowed.
+func WithACL(acl *ACL) Option {
+	return func(options *options) {
+		options.acl = acl
+	}
+}
+
+// Persists the broker's retained messages, client sessions and subscriptions in a bolt database at
+// the given path, so that they survive restarts. The state is restored when the broker starts
+// serving. Without this option, the broker's state is lost when it stops.
+func WithPersistence(path string) Option {
+	return func(options *options) {
+		options.persistencePath = path
+	}
+}
+
+// Secures the broker's WebSocket and TCP connections with TLS, using the given certificate. New
+// connections get the certificate's currently loaded certificate, so that it can be reloaded
+// without restarting the broker. Without this option, or if the certificate is nil, connections
+// are not secured.
+func WithTLS(certificate *tlscert.Certificate) Option {
+	return func(options *options) {
+		options.certificate = certificate
+	}
+}
+
+// Sets how often the broker publishes its statistics (see Stats) as retained messages on the
+// $SYS/broker/... topics, such as $SYS/broker/clients/connected. Without this option, they are
+// published every 30 seconds.
+func WithStatsInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.statsInterval = interval
+	}
+}
+
+// Auth controller that authenticates clients with another controller, and checks their topic
+// access against both that controller and an ACL. Each connection gets its own controller (see
+// clientListener), so that the ACL can be checked for the connection's client ID.
+// Implements auth.Controller from mochi-co/mqtt.
+type accessController struct {
+	auth auth.Controller
+
+	// Allows all topics if nil.
+	acl *ACL
+
+	// Broker and ID of the listener that the controller's connection came through, for looking up
+	// its client ID. Nil and empty for the template controller in the listener config.
+	broker     *mqtt.Server
+	listenerID string
+
+	// ID of the controller's client, once looked up. Only accessed by the client's goroutine, which
+	// is the only one checking its topic access.
+	clientID string
+}
+
+// Returns whether the given user may connect, according to the controller's auth controller.
+func (controller *accessController) Authenticate(user []byte, password []byte) bool {
+	return controller.auth.Authenticate(user, password)
+}
+
+// Returns whether the given user may publish (if write is true) or subscribe to the given topic,
+// according to both the controller's auth controller and its ACL.
+func (controller *accessController) ACL(user []byte, topic string, write bool) bool {
+	if !controller.auth.ACL(user, topic, write) {
+		return false
+	}
+
+	return controller.acl == nil ||
+		controller.acl.Allows(string(user), controller.client(), topic, write)
+}
+
+// Returns the ID of the controller's client, looked up among the clients of its listener on first
+// call. Returns empty string if the client was not found.
+func (controller *accessController) client() string {
+	if controller.clientID != "" || controller.broker == nil {
+		return controller.clientID
+	}
+
+	for _, client := range controller.broker.Clients.GetByListener(controller.listenerID) {
+		if client.AC == auth.Controller(controller) {
+			controller.clientID = client.ID
+			break
+		}
+	}
+
+	return controller.clientID
+}
+
+// Listener that gives each connection its own copy of the listener config's access controller, so
+// that the controller can tell which client it checks topic access for.
+type clientListener struct {
+	listeners.Listener
+
+	broker *mqtt.Server
+}
+
+// Serves the wrapped listener, passing each connection on to the given establish function with a
+// new access controller.
+func (listener clientListener) Serve(establish listeners.EstablishFunc) {
+	listener.Listener.Serve(func(id string, conn net.Conn, controller auth.Controller) error {
+		if template, ok := controller.(*accessController); ok {
+			connectionController := *template
+			connectionController.broker = listener.broker
+			connectionController.listenerID = id
+			controller = &connectionController
+		}
+
+		return establish(id, conn, controller)
+	})
+}
+
+// Creates an MQTT broker configured to listen for WebSocket and TCP connections on the given ports,
+// with the given options. Returns an error if setup failed.
+func New(socketPort string, tcpPort string, opts ...Option) (*mqtt.Server, error) {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// mochi-co/mqtt reads the $SYS interval (in milliseconds) from a package variable when creating
+	// a server.
+	if options.statsInterval >= time.Millisecond {
+		mqtt.SysTopicInterval = options.statsInterval / time.Millisecond
+	}
 	broker := mqtt.NewServer(nil)
 
-	listenerConfig, err := configureListener()
-	if err != nil {
-		return nil, err
+	if options.persistencePath != "" {
+		err := broker.AddStore(boltStore{bolt.New(options.persistencePath, nil)})
+		if err != nil {
+			return nil, fmt.Errorf("broker persistence setup failed: %w", err)
+		}
 	}
 
-	socket := listeners.NewWebsocket("socket1", ":"+socketPort)
-	err = broker.AddListener(socket, listenerConfig)
+	listenerConfig := configureListener(options)
+
+	var socket, tcp listeners.Listener
+	if options.certificate != nil {
+		tlsConfig := options.certificate.TLSConfig()
+		socket = newTLSWebsocketListener("socket1", ":"+socketPort, tlsConfig)
+		tcp = newTLSTCPListener("tcp1", ":"+tcpPort, tlsConfig)
+	} else {
+		socket = listeners.NewWebsocket("socket1", ":"+socketPort)
+		tcp = listeners.NewTCP("tcp1", ":"+tcpPort)
+	}
+
+	socket = clientListener{Listener: socket, broker: broker}
+	tcp = clientListener{Listener: tcp, broker: broker}
+
+	err := broker.AddListener(socket, listenerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("websocket listener setup failed: %w", err)
 	}
 
-	tcp := listeners.NewTCP("tcp1", ":"+tcpPort)
 	err = broker.AddListener(tcp, listenerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("tcp listener setup failed: %w", err)
@@ -46,29 +210,14 @@ func New(socketPort string, tcpPort string) (*mqtt.Server, error) {
 	return broker, nil
 }
 
-// Returns an MQTT listener config, or an error if config setup failed.
-// If in a production environment, configures TLS for the listener with the embedded TLS files.
-func configureListener() (*listeners.Config, error) {
-	config := &listeners.Config{Auth: new(auth.Allow)}
-
-	if os.Getenv("ENV") == "production" {
-		tlsCertificate, err := tlsFiles.ReadFile("tls/tls-cert.pem")
-		if err != nil {
-			return nil, fmt.Errorf("tls certificate setup failed: %w", err)
-		}
-
-		tlsKey, err := tlsFiles.ReadFile("tls/tls-key.pem")
-		if err != nil {
-			return nil, fmt.Errorf("tls key setup failed: %w", err)
-		}
-
-		config.TLS = &listeners.TLS{
-			Certificate: tlsCertificate,
-			PrivateKey:  tlsKey,
-		}
+// Returns an MQTT listener config with the given options.
+func configureListener(options options) *listeners.Config {
+	controller := &accessController{auth: options.auth, acl: options.acl}
+	if controller.auth == nil {
+		controller.auth = new(auth.Allow)
 	}
 
-	return config, nil
+	return &listeners.Config{Auth: controller}
 }
 
 // Registers handlers to log events on the given broker.
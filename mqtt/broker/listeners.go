@@ -0,0 +1,249 @@
+package broker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"github.com/mochi-co/mqtt/server/system"
+)
+
+// The TCP and WebSocket listeners of mochi-co/mqtt read their TLS certificate once, when they start
+// listening. The listeners below instead take a TLS config, so that the certificate can be looked
+// up for each new connection, and renewed without restarting the broker.
+
+// How long closing a WebSocket listener waits for its HTTP server to shut down.
+const websocketShutdownTimeout = 5 * time.Second
+
+// Upgrades incoming HTTP connections to MQTT WebSocket connections, accepting all origins.
+var websocketUpgrader = &websocket.Upgrader{
+	Subprotocols: []string{"mqtt"},
+	CheckOrigin:  func(*http.Request) bool { return true },
+}
+
+// Listener for MQTT connections over TLS-secured TCP.
+// Implements listeners.Listener from mochi-co/mqtt.
+type tlsTCPListener struct {
+	id        string
+	address   string
+	tlsConfig *tls.Config
+
+	// Config set by the broker, with the auth controller for new clients.
+	config *listeners.Config
+
+	// Nil until listening.
+	listener net.Listener
+
+	closeOnce sync.Once
+}
+
+// Listener for MQTT connections over TLS-secured WebSocket.
+// Implements listeners.Listener from mochi-co/mqtt.
+type tlsWebsocketListener struct {
+	id      string
+	address string
+
+	// Config set by the broker, with the auth controller for new clients.
+	config *listeners.Config
+
+	// HTTP server upgrading requests to WebSocket connections.
+	server *http.Server
+
+	// Nil until listening.
+	listener net.Listener
+
+	// The broker's handler for new connections. Nil until serving.
+	establish listeners.EstablishFunc
+
+	closeOnce sync.Once
+}
+
+// A WebSocket connection carrying MQTT packets in binary messages, wrapped as a net.Conn for the
+// broker.
+type websocketConn struct {
+	net.Conn
+
+	socket *websocket.Conn
+
+	// Reader of the binary message currently being read. Nil between messages.
+	reader io.Reader
+}
+
+// Returns a listener with the given ID for TLS connections over TCP on the given address.
+func newTLSTCPListener(id string, address string, tlsConfig *tls.Config) *tlsTCPListener {
+	return &tlsTCPListener{
+		id:        id,
+		address:   address,
+		tlsConfig: tlsConfig,
+		config:    &listeners.Config{Auth: new(auth.Allow)},
+	}
+}
+
+// Sets the listener's config. Disallows all clients if the config has no auth controller, like
+// the listeners of mochi-co/mqtt.
+func (listener *tlsTCPListener) SetConfig(config *listeners.Config) {
+	if config == nil {
+		return
+	}
+
+	listener.config = config
+	if listener.config.Auth == nil {
+		listener.config.Auth = new(auth.Disallow)
+	}
+}
+
+// Opens the listener's network address.
+func (listener *tlsTCPListener) Listen(*system.Info) error {
+	var err error
+	listener.listener, err = tls.Listen("tcp", listener.address, listener.tlsConfig)
+	return err
+}
+
+// Accepts new connections, passing them on to the given handler, until the listener is closed.
+func (listener *tlsTCPListener) Serve(establish listeners.EstablishFunc) {
+	for {
+		conn, err := listener.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go establish(listener.id, conn, listener.config.Auth)
+	}
+}
+
+// Returns the listener's ID.
+func (listener *tlsTCPListener) ID() string {
+	return listener.id
+}
+
+// Closes the listener's clients with the given function, and stops listening.
+func (listener *tlsTCPListener) Close(closeClients listeners.CloseFunc) {
+	listener.closeOnce.Do(func() {
+		closeClients(listener.id)
+
+		if listener.listener != nil {
+			listener.listener.Close()
+		}
+	})
+}
+
+// Returns a listener with the given ID for TLS connections over WebSocket on the given address.
+func newTLSWebsocketListener(
+	id string, address string, tlsConfig *tls.Config,
+) *tlsWebsocketListener {
+	listener := &tlsWebsocketListener{
+		id:      id,
+		address: address,
+		config:  &listeners.Config{Auth: new(auth.Allow)},
+	}
+	listener.server = &http.Server{
+		Handler:   http.HandlerFunc(listener.handleRequest),
+		TLSConfig: tlsConfig,
+	}
+
+	return listener
+}
+
+// Sets the listener's config. Disallows all clients if the config has no auth controller, like
+// the listeners of mochi-co/mqtt.
+func (listener *tlsWebsocketListener) SetConfig(config *listeners.Config) {
+	if config == nil {
+		return
+	}
+
+	listener.config = config
+	if listener.config.Auth == nil {
+		listener.config.Auth = new(auth.Disallow)
+	}
+}
+
+// Opens the listener's network address.
+func (listener *tlsWebsocketListener) Listen(*system.Info) error {
+	var err error
+	listener.listener, err = net.Listen("tcp", listener.address)
+	return err
+}
+
+// Serves HTTPS on the listener's address, passing upgraded WebSocket connections on to the given
+// handler, until the listener is closed.
+func (listener *tlsWebsocketListener) Serve(establish listeners.EstablishFunc) {
+	listener.establish = establish
+	listener.server.ServeTLS(listener.listener, "", "")
+}
+
+// Upgrades the given request to a WebSocket connection, and passes it on to the broker. Returns
+// when the connection closes.
+func (listener *tlsWebsocketListener) handleRequest(res http.ResponseWriter, req *http.Request) {
+	socket, err := websocketUpgrader.Upgrade(res, req, nil)
+	if err != nil {
+		return
+	}
+	defer socket.Close()
+
+	listener.establish(
+		listener.id,
+		&websocketConn{Conn: socket.UnderlyingConn(), socket: socket},
+		listener.config.Auth,
+	)
+}
+
+// Returns the listener's ID.
+func (listener *tlsWebsocketListener) ID() string {
+	return listener.id
+}
+
+// Closes the listener's clients with the given function, and shuts down its HTTP server.
+func (listener *tlsWebsocketListener) Close(closeClients listeners.CloseFunc) {
+	listener.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), websocketShutdownTimeout)
+		defer cancel()
+		listener.server.Shutdown(ctx)
+
+		closeClients(listener.id)
+	})
+}
+
+// Reads MQTT packet bytes from the connection's binary messages. Returns error if a message is not
+// binary.
+func (conn *websocketConn) Read(bytes []byte) (int, error) {
+	for {
+		if conn.reader == nil {
+			messageType, reader, err := conn.socket.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				return 0, errors.New("websocket message not binary")
+			}
+			conn.reader = reader
+		}
+
+		n, err := conn.reader.Read(bytes)
+		if errors.Is(err, io.EOF) {
+			conn.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Writes the given MQTT packet bytes to the connection as a binary message.
+func (conn *websocketConn) Write(bytes []byte) (int, error) {
+	err := conn.socket.WriteMessage(websocket.BinaryMessage, bytes)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(bytes), nil
+}
@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"time"
+
+	"github.com/mochi-co/mqtt/server/persistence"
+	"github.com/mochi-co/mqtt/server/persistence/bolt"
+	"github.com/mochi-co/mqtt/server/system"
+)
+
+// Persistence store that persists the broker's state in a bolt database, except for its
+// statistics. The statistics describe a single run of the broker, and restoring them would carry
+// over the previous run's start time and connected clients.
+// Implements persistence.Store from mochi-co/mqtt.
+type boltStore struct {
+	*bolt.Store
+}
+
+// Returns fresh statistics for a broker starting now, counting the persisted subscriptions,
+// in-flight messages and retained messages that the broker restores.
+func (store boltStore) ReadServerInfo() (persistence.ServerInfo, error) {
+	subscriptions, err := store.ReadSubscriptions()
+	if err != nil {
+		return persistence.ServerInfo{}, err
+	}
+
+	inflight, err := store.ReadInflight()
+	if err != nil {
+		return persistence.ServerInfo{}, err
+	}
+
+	retained, err := store.ReadRetained()
+	if err != nil {
+		return persistence.ServerInfo{}, err
+	}
+
+	return persistence.ServerInfo{
+		Info: system.Info{
+			Started:       time.Now().Unix(),
+			Subscriptions: int64(len(subscriptions)),
+			Inflight:      int64(len(inflight)),
+			Retained:      int64(len(retained)),
+		},
+		ID: persistence.KServerInfo,
+	}, nil
+}
+
+// Does nothing, as statistics are not restored.
+func (store boltStore) WriteServerInfo(persistence.ServerInfo) error {
+	return nil
+}
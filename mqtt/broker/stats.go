@@ -0,0 +1,108 @@
+package broker
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/mochi-co/mqtt/server"
+)
+
+// Statistics of an MQTT broker since it started, as published on its $SYS/broker/... topics.
+type Stats struct {
+	Version string `json:"version"`
+
+	// Unix timestamp (in seconds) of when the broker started.
+	Started int64 `json:"started"`
+
+	// Number of seconds since the broker started.
+	Uptime int64 `json:"uptime"`
+
+	// Number of clients currently connected.
+	ClientsConnected int64 `json:"clientsConnected"`
+
+	// Maximum number of clients connected at the same time.
+	ClientsMaximum int64 `json:"clientsMaximum"`
+
+	// Number of connections ever made to the broker.
+	ConnectionsTotal int64 `json:"connectionsTotal"`
+
+	// Number of packets received from and sent to clients.
+	MessagesReceived int64 `json:"messagesReceived"`
+	MessagesSent     int64 `json:"messagesSent"`
+
+	// Number of publish packets received from and sent to clients, and dropped before delivery.
+	PublishReceived int64 `json:"publishReceived"`
+	PublishSent     int64 `json:"publishSent"`
+	PublishDropped  int64 `json:"publishDropped"`
+
+	// Number of bytes received from and sent to clients.
+	BytesReceived int64 `json:"bytesReceived"`
+	BytesSent     int64 `json:"bytesSent"`
+
+	// Number of messages currently retained, including those on $SYS topics. Counted when the stats
+	// are read, so may differ from $SYS/broker/messages/retained/count, which the broker also
+	// increments when a retained message is replaced.
+	Retained int64 `json:"retained"`
+
+	// Number of messages sent to clients but not yet acknowledged.
+	Inflight int64 `json:"inflight"`
+
+	// Number of active subscriptions.
+	Subscriptions int64 `json:"subscriptions"`
+}
+
+// Returns the current statistics of the given broker.
+func ReadStats(broker *mqtt.Server) Stats {
+	// The broker replaces its statistics when restoring persisted state, so they are read from the
+	// broker on every call.
+	info := broker.System
+
+	started := atomic.LoadInt64(&info.Started)
+	return Stats{
+		Version:          info.Version,
+		Started:          started,
+		Uptime:           time.Now().Unix() - started,
+		ClientsConnected: atomic.LoadInt64(&info.ClientsConnected),
+		ClientsMaximum:   atomic.LoadInt64(&info.ClientsMax),
+		ConnectionsTotal: atomic.LoadInt64(&info.ConnectionsTotal),
+		MessagesReceived: atomic.LoadInt64(&info.MessagesRecv),
+		MessagesSent:     atomic.LoadInt64(&info.MessagesSent),
+		PublishReceived:  atomic.LoadInt64(&info.PublishRecv),
+		PublishSent:      atomic.LoadInt64(&info.PublishSent),
+		PublishDropped:   atomic.LoadInt64(&info.PublishDropped),
+		BytesReceived:    atomic.LoadInt64(&info.BytesRecv),
+		BytesSent:        atomic.LoadInt64(&info.BytesSent),
+		Retained:         countRetained(broker),
+		Inflight:         atomic.LoadInt64(&info.Inflight),
+		Subscriptions:    atomic.LoadInt64(&info.Subscriptions),
+	}
+}
+
+// Returns the number of messages currently retained by the given broker, including those on $SYS
+// topics. Counted from the broker's topics, as the broker's running count is also incremented when
+// a retained message is replaced.
+func countRetained(broker *mqtt.Server) int64 {
+	// Wildcards at the start of a filter do not match topics starting with $.
+	return int64(len(broker.Topics.Messages("#")) + len(broker.Topics.Messages("$SYS/#")))
+}
+
+// Returns an HTTP handler that responds to GET requests with the given broker's current
+// statistics as JSON.
+func StatsHandler(broker *mqtt.Server) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+
+		err := json.NewEncoder(res).Encode(ReadStats(broker))
+		if err != nil {
+			log.Println("Failed to write broker stats response:", err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package broker
+
+import (
+	"embed"
+
+	"github.com/dcs-team4/coffeetalk/tlscert"
+)
+
+// For production environment without configured TLS file paths: expects tls-cert.pem and
+// tls-key.pem in tls directory.
+//
+//go:embed all:tls
+var tlsFiles embed.FS
+
+// Loads the TLS certificate and key for the broker from the files at the given paths, or from the
+// embedded TLS files if both paths are empty. Returns error if only one path is given, or the files
+// are missing or invalid.
+func LoadCertificate(certificatePath string, keyPath string) (*tlscert.Certificate, error) {
+	return tlscert.Load(certificatePath, keyPath, tlsFiles)
+}
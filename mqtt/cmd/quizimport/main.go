@@ -0,0 +1,171 @@
+// Command quizimport adds questions from Open Trivia DB JSON dumps or CSV files to the question
+// bank of a running quiz server, through its admin HTTP API, so that the server's in-memory bank
+// stays in sync. Imported questions get fresh IDs, and questions with the same text as one already
+// in the bank are skipped. Nothing is added if any imported question is invalid.
+//
+// Usage, from the mqtt module:
+//
+//	go run ./cmd/quizimport [flags] file ...
+//
+// Flags are -server (the base URL of the HTTP API, http://localhost:1881 by default), -token (the
+// admin token, QUIZ_ADMIN_TOKEN by default), -format (opentdb or csv) and -category (to replace the
+// imported questions' categories). The format is guessed from each file's extension (.json for
+// Open Trivia DB, .csv for CSV) if not given. CSV files need a header row naming their columns:
+// question (required), answer, options, alternatives, category, difficulty, mode and language,
+// with options and alternatives separated by "|".
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/quiz"
+)
+
+// Formats of the files that can be imported.
+const (
+	formatOpenTDB = "opentdb"
+	formatCSV     = "csv"
+)
+
+func main() {
+	server := flag.String(
+		"server", "http://localhost:1881", "base URL of the quiz server's HTTP API",
+	)
+	token := flag.String(
+		"token", os.Getenv("QUIZ_ADMIN_TOKEN"), "quiz admin token (default: $QUIZ_ADMIN_TOKEN)",
+	)
+	format := flag.String(
+		"format", "", "format of the files: opentdb or csv (default: by extension)",
+	)
+	category := flag.String(
+		"category", "", "category to set on imported questions, replacing their own",
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(
+			flag.CommandLine.Output(),
+			"Usage: quizimport [flags] file ...",
+		)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *token == "" {
+		log.Fatalln("No admin token given: set -token or QUIZ_ADMIN_TOKEN")
+	}
+
+	imported := make([]quiz.Question, 0)
+	for _, path := range flag.Args() {
+		questions, err := readFile(path, *format)
+		if err != nil {
+			log.Fatalf("%v: %v\n", path, err)
+		}
+		imported = append(imported, questions...)
+	}
+
+	if *category != "" {
+		for i := range imported {
+			imported[i].Category = *category
+		}
+	}
+
+	result, err := importQuestions(*server, *token, imported)
+	if err != nil {
+		log.Fatalln("Import failed:", err)
+	}
+
+	fmt.Printf(
+		"Added %v questions to %v, skipped %v duplicates.\n",
+		len(result.Added),
+		*server,
+		result.Duplicates,
+	)
+}
+
+// Posts the given questions to the question import endpoint of the quiz server's HTTP API at the
+// given base URL, authenticated with the given admin token. Returns error if the request failed,
+// or the server rejected the questions.
+func importQuestions(
+	server string, token string, questions []quiz.Question,
+) (quiz.ImportResult, error) {
+	body, err := json.Marshal(questions)
+	if err != nil {
+		return quiz.ImportResult{}, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		strings.TrimSuffix(server, "/")+"/quiz/question-import",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return quiz.ImportResult{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return quiz.ImportResult{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(res.Body)
+		return quiz.ImportResult{}, errors.New(strings.TrimSpace(string(message)))
+	}
+
+	var result quiz.ImportResult
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return quiz.ImportResult{}, fmt.Errorf("invalid response from server: %w", err)
+	}
+
+	return result, nil
+}
+
+// Returns the questions in the file at the given path, read in the given format, or in the format
+// matching the file's extension if empty. Returns error if the file could not be read or parsed.
+func readFile(path string, format string) ([]quiz.Question, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			format = formatOpenTDB
+		case ".csv":
+			format = formatCSV
+		default:
+			return nil, fmt.Errorf(
+				"unknown file extension, set -format %v or %v", formatOpenTDB, formatCSV,
+			)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatOpenTDB:
+		return quiz.ImportOpenTDB(data)
+	case formatCSV:
+		return quiz.ImportCSV(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown format '%v'", format)
+	}
+}
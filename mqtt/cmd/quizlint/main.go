@@ -0,0 +1,72 @@
+// Command quizlint checks quiz question files for problems before they reach the quiz server.
+// Reports errors for questions that the server would fail to load, and warnings for likely
+// mistakes, such as duplicate question text. Exits with status 1 if any file has errors (or
+// warnings, with -strict), and 2 if a file could not be read.
+//
+// Usage, from the mqtt module:
+//
+//	go run ./cmd/quizlint [-strict] [file ...]
+//
+// Checks the questions embedded in the quiz server (from quiz/questions.json, as compiled into this
+// command) if no files are given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/quiz"
+)
+
+func main() {
+	strict := flag.Bool("strict", false, "exit with status 1 on warnings, not just errors")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: quizlint [-strict] [file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		os.Exit(lintData("embedded questions", quiz.EmbeddedQuestions(), *strict))
+	}
+
+	status := 0
+	for _, path := range paths {
+		fileStatus := lintFile(path, *strict)
+		if fileStatus > status {
+			status = fileStatus
+		}
+	}
+
+	os.Exit(status)
+}
+
+// Prints the problems found in the question file at the given path, and returns the exit status
+// for the file: 0 if it has no errors (and no warnings, if strict), 1 if it has, and 2 if it could
+// not be read.
+func lintFile(path string, strict bool) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", path, err)
+		return 2
+	}
+
+	return lintData(path, data, strict)
+}
+
+// Prints the problems found in the given question data, prefixed by the given name, and returns
+// the exit status for it: 0 if it has no errors (and no warnings, if strict), and 1 if it has.
+func lintData(name string, data []byte, strict bool) int {
+	status := 0
+	for _, issue := range quiz.LintQuestions(data) {
+		fmt.Printf("%v: %v\n", name, issue)
+
+		if issue.Severity == quiz.LintError || strict {
+			status = 1
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/quiz"
+)
+
+// Server configuration, read from environment variables.
+type environment struct {
+	// Port for MQTT over WebSocket.
+	socketPort string
+
+	// Port for MQTT over TCP.
+	tcpPort string
+
+	// Port for the HTTP API.
+	httpPort string
+
+	// Directory for persistent data, such as the quiz history database and the broker's retained
+	// messages.
+	dataDir string
+
+	// Path to a JSON file with quiz configuration. Optional.
+	quizConfigPath string
+
+	// Name of the quiz room served by this server. Overrides the quiz config file if set.
+	quizRoom string
+
+	// Format of the payloads published on the quiz topics. Overrides the quiz config file if set.
+	payloadFormat quiz.PayloadFormat
+
+	// Bearer token for the quiz admin HTTP API. Overrides the quiz config file if set.
+	adminToken string
+
+	// Base URL where clients fetch quiz media files. Overrides the quiz config file if set.
+	mediaURL string
+
+	// Path to a file with the usernames and bcrypt password hashes of MQTT users. Optional.
+	credentialsPath string
+
+	// Secret shared with the web server, for verifying the tokens it issues to its MQTT clients.
+	// Optional.
+	tokenSecret string
+
+	// Path to a JSON file with the MQTT topic ACL. Optional; defaults to defaultACL.
+	aclPath string
+
+	// Paths to the PEM-encoded TLS certificate and key files used in production, reloaded when they
+	// change. Always set by docker-compose-prod.yml, to the files in TLS_DIR. Optional when running
+	// in production mode without it (such as when testing TLS locally), in which case the TLS files
+	// embedded in the broker package at build time are used.
+	tlsCertificatePath string
+	tlsKeyPath         string
+
+	// How often the broker publishes its statistics on the $SYS topics, as a Go duration such as
+	// "10s". Optional.
+	statsInterval string
+}
+
+// Gets server configuration from environment variables, using defaults for those not set.
+func getEnv() environment {
+	env := environment{
+		socketPort:         getEnvOrDefault("SOCKET_PORT", "1882"),
+		tcpPort:            getEnvOrDefault("TCP_PORT", "1883"),
+		httpPort:           getEnvOrDefault("HTTP_PORT", "1881"),
+		dataDir:            getEnvOrDefault("DATA_DIR", "data"),
+		quizConfigPath:     os.Getenv("QUIZ_CONFIG"),
+		quizRoom:           os.Getenv("QUIZ_ROOM"),
+		payloadFormat:      quiz.PayloadFormat(os.Getenv("QUIZ_PAYLOAD_FORMAT")),
+		adminToken:         os.Getenv("QUIZ_ADMIN_TOKEN"),
+		mediaURL:           os.Getenv("QUIZ_MEDIA_URL"),
+		credentialsPath:    os.Getenv("MQTT_CREDENTIALS"),
+		tokenSecret:        os.Getenv("MQTT_TOKEN_SECRET"),
+		aclPath:            os.Getenv("MQTT_ACL"),
+		tlsCertificatePath: os.Getenv("TLS_CERT_FILE"),
+		tlsKeyPath:         os.Getenv("TLS_KEY_FILE"),
+		statsInterval:      os.Getenv("MQTT_STATS_INTERVAL"),
+	}
+
+	if env.payloadFormat != "" && env.payloadFormat != quiz.FormatText {
+		env.payloadFormat = quiz.FormatJSON
+	}
+
+	return env
+}
+
+// Returns the value of the environment variable with the given name, or the given default value if
+// it is not set.
+func getEnvOrDefault(name string, defaultValue string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
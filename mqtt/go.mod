@@ -4,10 +4,21 @@ go 1.18
 
 require (
 	github.com/dcs-team4/coffeetalk/stm v1.1.0
+	github.com/dcs-team4/coffeetalk/tlscert v0.0.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/mochi-co/mqtt v1.2.1
+	github.com/robfig/cron/v3 v3.0.1
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/crypto v0.14.0
+	golang.org/x/text v0.14.0
 )
 
 require (
-	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/asdine/storm v2.1.2+incompatible // indirect
+	github.com/asdine/storm/v3 v3.2.1 // indirect
 	github.com/rs/xid v1.4.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
+
+// Shared module in this repository, not published separately.
+replace github.com/dcs-team4/coffeetalk/tlscert => ../tlscert
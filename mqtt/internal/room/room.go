@@ -0,0 +1,146 @@
+// Package room holds what the server's rooms (quiz sessions and polls) share in talking to MQTT
+// clients: JSON message publishing, replies to individual clients, the reply topics' access rules,
+// and admin checks.
+package room
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/broker"
+	mqtt "github.com/mochi-co/mqtt/server"
+)
+
+// Base struct to embed in all JSON message types.
+type Message struct {
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+}
+
+// Type of the JSON message posted by the server when rejecting a message from a client.
+const MsgError string = "error"
+
+// Message posted by the server on a client's reply topic when rejecting a message from the client.
+type ErrorMessage struct {
+	Message        // Type: MsgError
+	Request string `json:"request"` // Type of the rejected message.
+	Error   string `json:"error"`
+}
+
+// A message sent by an MQTT client on a room's status topic to start or control a session.
+type Command struct {
+	// Empty for commands not sent by a client, such as scheduled starts.
+	ClientID string
+
+	// Whether the client is logged in as an admin, allowed to control any session in the room.
+	Admin bool
+
+	// Type of the command message.
+	Kind string
+}
+
+// Returns the topic with the given reply topic prefix where the server replies to the client with
+// the given ID.
+func ReplyTopic(prefix string, clientID string) string {
+	return prefix + clientID
+}
+
+// Returns the ACL rules for the reply topics with the given prefix, letting each client only read
+// its own reply topic. Should be placed before rules granting access to the topics around them.
+func ReplyACLRules(prefix string) []broker.ACLRule {
+	return []broker.ACLRule{
+		{Filter: ReplyTopic(prefix, broker.ClientIDPlaceholder), Access: broker.AccessRead},
+		{Filter: prefix + "#", Access: broker.AccessNone},
+	}
+}
+
+// Publishes a room's JSON messages to MQTT clients, and identifies the clients logged in as the
+// room's admins.
+type Messenger struct {
+	// The MQTT broker where messages are to be published.
+	broker *mqtt.Server
+
+	// Name of the room's messages in log messages, such as "quiz".
+	name string
+
+	// Version of the room's JSON message format, set on error messages.
+	version int
+
+	// Prefix of the room's reply topics. See ReplyTopic.
+	replyTopicPrefix string
+
+	// MQTT usernames of the room's admins.
+	admins map[string]bool
+}
+
+// Returns a new messenger publishing to the given broker, for the room with the given name in log
+// messages, message version, reply topic prefix and admin usernames.
+func NewMessenger(
+	broker *mqtt.Server, name string, version int, replyTopicPrefix string, admins []string,
+) *Messenger {
+	adminSet := make(map[string]bool)
+	for _, admin := range admins {
+		adminSet[admin] = true
+	}
+
+	return &Messenger{
+		broker:           broker,
+		name:             name,
+		version:          version,
+		replyTopicPrefix: replyTopicPrefix,
+		admins:           adminSet,
+	}
+}
+
+// Serializes the given message as JSON, and publishes it to the given topic on the messenger's
+// broker.
+func (messenger *Messenger) PublishJSON(topic string, message any, retain bool) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to serialize %v message (topic: %v): %v\n", messenger.name, topic, err)
+		return
+	}
+
+	messenger.broker.Publish(topic, payload, retain)
+}
+
+// Publishes the given message to the reply topic of the client with the given ID.
+func (messenger *Messenger) Reply(clientID string, message any) {
+	messenger.PublishJSON(ReplyTopic(messenger.replyTopicPrefix, clientID), message, false)
+}
+
+// Publishes an error message to the reply topic of the client with the given ID, telling it that
+// its message of the given type was rejected. Does nothing if the client ID is empty, as for
+// commands not sent by a client.
+func (messenger *Messenger) ReplyError(clientID string, request string, reason string) {
+	if clientID == "" {
+		return
+	}
+
+	messenger.Reply(clientID, ErrorMessage{
+		Message: Message{Version: messenger.version, Type: MsgError},
+		Request: request,
+		Error:   reason,
+	})
+}
+
+// Returns whether the client with the given ID is connected to the messenger's broker with the
+// username of an admin.
+func (messenger *Messenger) IsAdmin(clientID string) bool {
+	if len(messenger.admins) == 0 {
+		return false
+	}
+
+	client, ok := messenger.broker.Clients.Get(clientID)
+	if !ok {
+		return false
+	}
+
+	return messenger.admins[string(client.Username)]
+}
+
+// Returns the command of the given kind sent by the client with the given ID, noting whether the
+// client is an admin.
+func (messenger *Messenger) Command(clientID string, kind string) Command {
+	return Command{ClientID: clientID, Admin: messenger.IsAdmin(clientID), Kind: kind}
+}
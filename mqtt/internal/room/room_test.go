@@ -0,0 +1,37 @@
+package room
+
+import (
+	"testing"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/broker"
+)
+
+func TestReplyACLRules(t *testing.T) {
+	acl := &broker.ACL{Rules: ReplyACLRules("room/replies/")}
+	acl.Rules = append(acl.Rules, broker.ACLRule{Filter: "#", Access: broker.AccessReadWrite})
+
+	tests := []struct {
+		clientID string
+		topic    string
+		write    bool
+		want     bool
+	}{
+		{"c1", "room/replies/c1", false, true},
+		{"c1", "room/replies/c1", true, false},
+		{"c1", "room/replies/c2", false, false},
+		{"c1", "room/replies/+", false, false},
+		{"c1", "room/replies/#", false, false},
+		{"c1", "room/#", false, false},
+		{"c1", "room/status", true, true},
+	}
+
+	for _, test := range tests {
+		got := acl.Allows("user", test.clientID, test.topic, test.write)
+		if got != test.want {
+			t.Errorf(
+				"Allows(%q, %q, write=%v) = %v, want %v",
+				test.clientID, test.topic, test.write, got, test.want,
+			)
+		}
+	}
+}
@@ -2,36 +2,268 @@ package main
 
 import (
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/dcs-team4/coffeetalk/mqtt/broker"
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	"github.com/dcs-team4/coffeetalk/mqtt/poll"
 	"github.com/dcs-team4/coffeetalk/mqtt/quiz"
+	"github.com/dcs-team4/coffeetalk/tlscert"
 	mqtt "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/events"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
 )
 
 func main() {
-	socketPort, tcpPort := getEnv()
+	env := getEnv()
 
 	// Sets up channel to keep running the server until it crashes, or a cancel signal is received.
 	close := make(chan struct{}, 1)
 	go handleCancelSignal(close)
 
-	mqttBroker := runBroker(socketPort, tcpPort, close)
-	runQuizMachine(mqttBroker, close)
+	store := openStore(env.dataDir)
+	questionBank := openQuestionBank(env.dataDir)
+	mediaDir := createMediaDir(env.dataDir)
+	certificate := loadCertificate(env)
+
+	mqttBroker := runBroker(
+		env.socketPort,
+		env.tcpPort,
+		newAuth(env),
+		loadACL(env),
+		filepath.Join(env.dataDir, "broker.db"),
+		certificate,
+		parseStatsInterval(env),
+		close,
+	)
+	quizConfig := getQuizConfig(env, store, questionBank, mediaDir)
+	quizmachine := runQuizMachine(mqttBroker, quizConfig, close)
+	runPollMachine(mqttBroker, poll.Config{Admins: quizConfig.Admins}, close)
+	runHTTPServer(env.httpPort, mqttBroker, quizmachine, certificate, close)
 
 	// Waits until server cancels/crashes.
 	<-close
 
 	mqttBroker.Close()
+	store.Close()
 	log.Println("Server closed.")
 }
 
-// Runs MQTT broker concurrently on the given ports, and returns it.
-// Sends on the given close channel if it crashes.
-func runBroker(socketPort string, tcpPort string, close chan<- struct{}) *mqtt.Server {
-	mqttBroker, err := broker.New(socketPort, tcpPort)
+// Opens the quiz store in the given data directory, creating the directory if necessary.
+func openStore(dataDir string) *quiz.Store {
+	err := os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		log.Panicln("Failed to create data directory:", err)
+	}
+
+	store, err := quiz.OpenStore(filepath.Join(dataDir, "quiz.db"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	return store
+}
+
+// Opens the quiz question bank in the given data directory, seeding it with the embedded questions
+// if it does not exist. Expects the data directory to exist.
+func openQuestionBank(dataDir string) *quiz.QuestionBank {
+	questionBank, err := quiz.OpenQuestionBank(filepath.Join(dataDir, "questions.json"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	return questionBank
+}
+
+// Creates the directory for quiz media files in the given data directory, if it does not exist, and
+// returns its path.
+func createMediaDir(dataDir string) string {
+	mediaDir := filepath.Join(dataDir, "media")
+
+	err := os.MkdirAll(mediaDir, 0700)
+	if err != nil {
+		log.Panicln("Failed to create media directory:", err)
+	}
+
+	return mediaDir
+}
+
+// Returns the quiz config from the config file given in the environment (if any), overridden by
+// quiz environment variables, and using the given store, question bank and media directory.
+func getQuizConfig(
+	env environment, store *quiz.Store, questionBank *quiz.QuestionBank, mediaDir string,
+) quiz.Config {
+	var config quiz.Config
+	if env.quizConfigPath != "" {
+		var err error
+		config, err = quiz.LoadConfig(env.quizConfigPath)
+		if err != nil {
+			log.Panicln(err)
+		}
+	}
+
+	if env.quizRoom != "" {
+		config.Room = env.quizRoom
+	}
+	if env.payloadFormat != "" {
+		config.PayloadFormat = env.payloadFormat
+	}
+	if env.adminToken != "" {
+		config.AdminToken = env.adminToken
+	}
+	config.Store = store
+	if env.mediaURL != "" {
+		config.MediaURL = env.mediaURL
+	}
+	checkMediaURL(config.MediaURL)
+	config.Questions = questionBank
+	config.MediaDir = mediaDir
+
+	return config
+}
+
+// Panics if the given quiz media URL is set, but not an absolute URL. Logs a warning if it is not
+// set, as clients are then given media URLs relative to the web page, which is served by the web
+// server rather than the quiz HTTP API.
+func checkMediaURL(mediaURL string) {
+	if mediaURL == "" {
+		log.Println(
+			"WARNING: QUIZ_MEDIA_URL is not set, so question media is linked relative to the " +
+				"web page, where it is not served. Set it to the HTTP API's absolute media URL, " +
+				"such as https://example.com:1881/quiz/media/",
+		)
+		return
+	}
+
+	parsed, err := url.Parse(mediaURL)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		log.Panicf(
+			"Invalid quiz media URL '%v': must be absolute, such as %v\n",
+			mediaURL,
+			"https://example.com:1881/quiz/media/",
+		)
+	}
+}
+
+// Loads the TLS certificate from the files given in the environment, or the embedded TLS files if
+// none are given, and reloads it whenever the files change or the process receives SIGHUP.
+// Returns nil, serving without TLS, if not in a production environment.
+func loadCertificate(env environment) *tlscert.Certificate {
+	if os.Getenv("ENV") != "production" {
+		return nil
+	}
+
+	certificate, err := broker.LoadCertificate(env.tlsCertificatePath, env.tlsKeyPath)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	go certificate.Watch()
+
+	return certificate
+}
+
+// Returns the auth controller for MQTT clients, using the credentials file and token secret given
+// in the environment. Returns nil, allowing all clients, if neither is given.
+func newAuth(env environment) auth.Controller {
+	if env.credentialsPath == "" && env.tokenSecret == "" {
+		log.Println("No MQTT credentials or token secret configured, allowing all clients")
+		return nil
+	}
+
+	controller, err := broker.NewAuth(env.credentialsPath, env.tokenSecret)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	return controller
+}
+
+// Returns the MQTT topic ACL from the file given in the environment, or defaultACL if none is
+// given.
+func loadACL(env environment) *broker.ACL {
+	if env.aclPath == "" {
+		return defaultACL()
+	}
+
+	acl, err := broker.LoadACL(env.aclPath)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	return acl
+}
+
+// Returns the ACL used if none is configured, letting clients use all topics except for publishing
+// to the topics where only the quiz and poll servers publish, and subscribing to other clients'
+// reply topics.
+func defaultACL() *broker.ACL {
+	serverTopics := []string{
+		quiz.QuestionTopic,
+		quiz.AnswerTopic,
+		quiz.LeaderboardTopic,
+		quiz.ResultsTopic,
+		quiz.StateTopic,
+		poll.ResultsTopic,
+	}
+
+	acl := &broker.ACL{}
+	for _, topic := range serverTopics {
+		acl.Rules = append(acl.Rules, broker.ACLRule{Filter: topic, Access: broker.AccessRead})
+	}
+	for _, prefix := range []string{quiz.ReplyTopicPrefix, poll.ReplyTopicPrefix} {
+		acl.Rules = append(acl.Rules, room.ReplyACLRules(prefix)...)
+	}
+	acl.Rules = append(acl.Rules, broker.ACLRule{Filter: "#", Access: broker.AccessReadWrite})
+
+	return acl
+}
+
+// Returns the interval for publishing broker statistics given in the environment, or 0 to use the
+// broker's default if none is given.
+func parseStatsInterval(env environment) time.Duration {
+	if env.statsInterval == "" {
+		return 0
+	}
+
+	interval, err := time.ParseDuration(env.statsInterval)
+	if err != nil || interval < time.Second {
+		log.Panicf("Invalid MQTT stats interval '%v': must be at least 1s\n", env.statsInterval)
+	}
+
+	return interval
+}
+
+// Runs MQTT broker concurrently on the given ports, with the given auth controller (allowing all
+// clients if nil) and ACL, and returns it. Persists the broker's retained messages and sessions in
+// the database at the given path, restoring them before serving. Secures connections with the
+// given TLS certificate, unless nil, and publishes statistics on the $SYS topics at the given
+// interval (or the default if 0). Sends on the given close channel if it crashes.
+func runBroker(
+	socketPort string,
+	tcpPort string,
+	authController auth.Controller,
+	acl *broker.ACL,
+	persistencePath string,
+	certificate *tlscert.Certificate,
+	statsInterval time.Duration,
+	close chan<- struct{},
+) *mqtt.Server {
+	mqttBroker, err := broker.New(
+		socketPort,
+		tcpPort,
+		broker.WithAuth(authController),
+		broker.WithACL(acl),
+		broker.WithPersistence(persistencePath),
+		broker.WithTLS(certificate),
+		broker.WithStatsInterval(statsInterval),
+	)
 	if err != nil {
 		log.Panicln(err)
 	}
@@ -46,10 +278,13 @@ func runBroker(socketPort string, tcpPort string, close chan<- struct{}) *mqtt.S
 	return mqttBroker
 }
 
-// Runs quiz state machine concurrently, and listens for quiz start messages on the given broker.
-// Sends on the given close channel if it crashes.
-func runQuizMachine(mqttBroker *mqtt.Server, close chan<- struct{}) {
-	quizmachine := quiz.NewMachine(mqttBroker)
+// Runs quiz state machine concurrently with the given config, listens for quiz messages and client
+// disconnects on the given broker, and returns the machine. Sends on the given close channel if it
+// crashes.
+func runQuizMachine(
+	mqttBroker *mqtt.Server, config quiz.Config, close chan<- struct{},
+) *quiz.QuizMachine {
+	quizmachine := quiz.NewMachine(mqttBroker, config)
 
 	go func() {
 		err := quizmachine.Run()
@@ -57,23 +292,84 @@ func runQuizMachine(mqttBroker *mqtt.Server, close chan<- struct{}) {
 		close <- struct{}{}
 	}()
 
-	mqttBroker.Events.OnMessage = quizmachine.StartQuizHandler()
+	mqttBroker.Events.OnProcessMessage = quizmachine.MessageHandler()
+
+	// Keeps the broker's existing disconnect handler (for logging) alongside the quiz machine's.
+	logDisconnect := mqttBroker.Events.OnDisconnect
+	handleQuizDisconnect := quizmachine.DisconnectHandler()
+	mqttBroker.Events.OnDisconnect = func(client events.Client, err error) {
+		if logDisconnect != nil {
+			logDisconnect(client, err)
+		}
+		handleQuizDisconnect(client, err)
+	}
+
 	log.Println("Running quiz state machine...")
+	return quizmachine
 }
 
-// Gets ports from environment variables.
-func getEnv() (socketPort string, tcpPort string) {
-	socketPort = os.Getenv("SOCKET_PORT")
-	if socketPort == "" {
-		socketPort = "1882"
-	}
+// Runs poll state machine concurrently with the given config, and listens for poll messages on the
+// given broker alongside the quiz machine's. Expects the quiz machine to be running. Sends on the
+// given close channel if it crashes.
+func runPollMachine(mqttBroker *mqtt.Server, config poll.Config, close chan<- struct{}) {
+	pollmachine := poll.NewMachine(mqttBroker, config)
+
+	go func() {
+		err := pollmachine.Run()
+		log.Println("Poll state machine failed:", err)
+		close <- struct{}{}
+	}()
 
-	tcpPort = os.Getenv("TCP_PORT")
-	if tcpPort == "" {
-		tcpPort = "1883"
+	// Keeps the quiz machine's message handler, which rejects packets it consumes, alongside the
+	// poll machine's.
+	handleQuizMessage := mqttBroker.Events.OnProcessMessage
+	handlePollMessage := pollmachine.MessageHandler()
+	mqttBroker.Events.OnProcessMessage = func(
+		client events.Client, packet events.Packet,
+	) (events.Packet, error) {
+		if handleQuizMessage != nil {
+			var err error
+			packet, err = handleQuizMessage(client, packet)
+			if err != nil {
+				return packet, err
+			}
+		}
+		return handlePollMessage(client, packet)
 	}
 
-	return socketPort, tcpPort
+	log.Println("Running poll state machine...")
+}
+
+// Serves the quiz HTTP API and the given broker's statistics (at /broker/stats) concurrently on the
+// given port, using the given TLS certificate unless nil. Sends on the given close channel if it
+// crashes.
+func runHTTPServer(
+	port string,
+	mqttBroker *mqtt.Server,
+	quizmachine *quiz.QuizMachine,
+	certificate *tlscert.Certificate,
+	close chan<- struct{},
+) {
+	mux := http.NewServeMux()
+	quizmachine.RegisterRoutes(mux)
+	mux.HandleFunc("/broker/stats", broker.StatsHandler(mqttBroker))
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+
+	go func() {
+		var err error
+		if certificate != nil {
+			server.TLSConfig = certificate.TLSConfig()
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		log.Println("HTTP server failed:", err)
+		close <- struct{}{}
+	}()
+
+	log.Printf("HTTP API listening on port %v...\n", port)
 }
 
 // Sends on the given listener channel when a system cancel signal is received.
@@ -0,0 +1,4 @@
+// Package poll defines a state machine for live audience polls, where anyone can ask a question
+// with a set of options, and see the votes come in. It uses a mochi-co/mqtt broker to publish the
+// tally of the current poll to a set of defined poll topics.
+package poll
@@ -0,0 +1,358 @@
+package poll
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	"github.com/dcs-team4/coffeetalk/stm"
+	mqtt "github.com/mochi-co/mqtt/server"
+)
+
+// Configuration options for a poll state machine.
+type Config struct {
+	// How long polls are open for when started without a duration. Defaults to DefaultDuration
+	// if 0.
+	Duration time.Duration
+
+	// MQTT usernames of the admins, allowed to close any poll, not just the ones they started.
+	Admins []string
+}
+
+// How long polls are open for if neither the config nor the start message gives a duration.
+const DefaultDuration = time.Minute
+
+// Limits on the polls that clients can start.
+const (
+	maxDuration = 10 * time.Minute
+	maxOptions  = 10
+)
+
+// Capacity of the machine's vote channel. Buffered, so that votes from many clients arriving at
+// once are not dropped.
+const voteBufferSize = 64
+
+// State machine for live audience polls. Runs one poll at a time.
+// Implements stm.StateMachine.
+type PollMachine struct {
+	// Map of poll machine state IDs to the functions that should run for those states.
+	states stm.States[*PollMachine]
+
+	// Receives start messages to start a new poll.
+	start chan command
+
+	// Receives close messages for the current poll.
+	commands chan command
+
+	// Receives votes submitted by clients.
+	votes chan vote
+
+	// Expires when the current poll times out.
+	timer *time.Timer
+
+	// The current poll while open, or the last poll once closed. Nil if no poll has been started.
+	poll *poll
+
+	// ID of the most recently started poll. Incremented for every poll.
+	lastPollID int
+
+	// The MQTT broker where poll results are to be published.
+	broker *mqtt.Server
+
+	// How long polls are open for when started without a duration.
+	defaultDuration time.Duration
+
+	// Publishes the machine's JSON messages, and identifies the admins, allowed to close any poll.
+	messenger *room.Messenger
+}
+
+// A poll, with the votes cast in it.
+type poll struct {
+	id       int
+	question string
+	options  []string
+
+	// Client ID of the client that started the poll.
+	creator string
+
+	// Index of the chosen option, by the client ID of the voter.
+	votes map[string]int
+
+	deadline time.Time
+	open     bool
+}
+
+// A start or close message sent by an MQTT client on the poll status topic. Its kind is
+// MsgStartPoll or MsgClosePoll.
+type command struct {
+	room.Command
+
+	// Question and options of the poll, if the command starts a poll.
+	startOptions StartPollMessage
+
+	// ID of the poll to close, if the command closes a poll.
+	pollID int
+}
+
+// A vote submitted by an MQTT client.
+type vote struct {
+	clientID string
+	message  VoteMessage
+}
+
+// IDs of the poll machine's states.
+// Uses iota for automatic enumeration, +1 to avoid potential clash with zero value.
+const (
+	idleState stm.StateID = iota + 1
+	openState
+)
+
+// Returns a new poll state machine, with all states and channels initialized.
+// Attaches the given broker to the machine, and assumes it is valid to send on.
+func NewMachine(broker *mqtt.Server, config Config) *PollMachine {
+	if config.Duration == 0 {
+		config.Duration = DefaultDuration
+	}
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	messenger := room.NewMessenger(broker, "poll", MessageVersion, ReplyTopicPrefix, config.Admins)
+
+	return &PollMachine{
+		states: stm.States[*PollMachine]{
+			idleState: runIdleState,
+			openState: runOpenState,
+		},
+		start:           make(chan command),
+		commands:        make(chan command),
+		votes:           make(chan vote, voteBufferSize),
+		timer:           timer,
+		broker:          broker,
+		defaultDuration: config.Duration,
+		messenger:       messenger,
+	}
+}
+
+// Returns the poll machine's configured states.
+func (machine *PollMachine) States() stm.States[*PollMachine] {
+	return machine.states
+}
+
+// Runs the given poll state machine. Keeps running through every configured state function,
+// transitioning to new states as they return, until an error occurs.
+func (machine *PollMachine) Run() error {
+	machine.restoreRetained()
+
+	startState := idleState
+	err := stm.RunMachine(machine, startState)
+	return err
+}
+
+// Restores the last poll retained by the broker from before the machine started, if the broker
+// persists its retained messages, so that new polls continue from its ID. A poll that was still
+// open is closed with the tally it had, as its individual votes can no longer be counted. Clears
+// the retained poll if it is not a valid poll message.
+func (machine *PollMachine) restoreRetained() {
+	retained := machine.broker.Topics.Messages(ResultsTopic)
+	if len(retained) == 0 {
+		return
+	}
+
+	var message PollMessage
+	err := json.Unmarshal(retained[0].Payload, &message)
+	if err != nil || message.Version != MessageVersion || message.Type != MsgPoll {
+		log.Println("Clearing invalid retained poll")
+		machine.broker.Publish(ResultsTopic, []byte{}, true)
+		return
+	}
+
+	machine.lastPollID = message.PollID
+
+	if message.Open {
+		log.Printf("Closing poll %v interrupted by restart\n", message.PollID)
+		message.Open = false
+		message.Deadline = 0
+		machine.messenger.PublishJSON(ResultsTopic, message, true)
+	}
+}
+
+// Waits for a start message, then opens a new poll and returns the Open state as the next state.
+// Rejects close messages and votes, as there is no open poll.
+func runIdleState(machine *PollMachine) (nextState stm.StateID, err error) {
+	for {
+		select {
+		case command := <-machine.start:
+			if machine.startPoll(command) {
+				return openState, nil
+			}
+		case command := <-machine.commands:
+			machine.messenger.ReplyError(command.ClientID, command.Kind, "no poll is open")
+		case vote := <-machine.votes:
+			machine.messenger.ReplyError(vote.clientID, MsgVote, "no poll is open")
+		}
+	}
+}
+
+// Collects votes in the current poll, publishing the tally as they come in, until the poll times
+// out or is closed by its creator or an admin. Then publishes the final tally, and returns the
+// Idle state as the next state.
+func runOpenState(machine *PollMachine) (nextState stm.StateID, err error) {
+	for {
+		select {
+		case <-machine.timer.C:
+			log.Printf("Poll %v timed out\n", machine.poll.id)
+			machine.closePoll()
+			return idleState, nil
+		case command := <-machine.start:
+			machine.messenger.ReplyError(command.ClientID, command.Kind, "a poll is already open")
+		case vote := <-machine.votes:
+			machine.recordVote(vote)
+		case command := <-machine.commands:
+			if command.pollID != machine.poll.id {
+				machine.messenger.ReplyError(command.ClientID, command.Kind, "poll is not open")
+				continue
+			}
+			if command.ClientID != machine.poll.creator && !command.Admin {
+				machine.messenger.ReplyError(
+					command.ClientID, command.Kind, "only the poll's creator can close it",
+				)
+				continue
+			}
+
+			if !machine.timer.Stop() {
+				<-machine.timer.C
+			}
+			log.Printf("Poll %v closed by client ID %v\n", machine.poll.id, command.ClientID)
+			machine.closePoll()
+			return idleState, nil
+		}
+	}
+}
+
+// Opens a new poll with the question and options of the given start command, starts its timer,
+// and publishes it. Returns false, and replies to the sender with an error, if the poll is
+// invalid.
+func (machine *PollMachine) startPoll(command command) bool {
+	duration, err := machine.validateStart(command.startOptions)
+	if err != nil {
+		log.Printf("Ignoring poll start from client ID %v: %v\n", command.ClientID, err)
+		machine.messenger.ReplyError(command.ClientID, command.Kind, err.Error())
+		return false
+	}
+
+	options := make([]string, 0, len(command.startOptions.Options))
+	for _, option := range command.startOptions.Options {
+		options = append(options, strings.TrimSpace(option))
+	}
+
+	machine.lastPollID++
+	machine.poll = &poll{
+		id:       machine.lastPollID,
+		question: strings.TrimSpace(command.startOptions.Question),
+		options:  options,
+		creator:  command.ClientID,
+		votes:    make(map[string]int),
+		deadline: time.Now().Add(duration),
+		open:     true,
+	}
+	machine.timer.Reset(duration)
+
+	log.Printf("Poll %v started by client ID %v\n", machine.poll.id, command.ClientID)
+	machine.publishPoll()
+	return true
+}
+
+// Returns how long the poll in the given start message should be open for, or an error if the
+// poll has no question, too few or too many options, blank or duplicate options, or a negative
+// duration. Durations above the maximum are capped.
+func (machine *PollMachine) validateStart(message StartPollMessage) (time.Duration, error) {
+	if strings.TrimSpace(message.Question) == "" {
+		return 0, errors.New("question is required")
+	}
+
+	if len(message.Options) < 2 || len(message.Options) > maxOptions {
+		return 0, fmt.Errorf("between 2 and %v options are required", maxOptions)
+	}
+
+	seen := make(map[string]bool)
+	for _, option := range message.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return 0, errors.New("options cannot be empty")
+		}
+
+		key := strings.ToLower(option)
+		if seen[key] {
+			return 0, fmt.Errorf("duplicate option '%v'", option)
+		}
+		seen[key] = true
+	}
+
+	if message.Duration < 0 {
+		return 0, errors.New("duration cannot be negative")
+	} else if message.Duration == 0 {
+		return machine.defaultDuration, nil
+	}
+
+	duration := time.Duration(message.Duration) * time.Second
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+	return duration, nil
+}
+
+// Records the given vote in the current poll, replacing any previous vote from the same client,
+// and publishes the updated tally. Replies to the voter with an error if the vote is not for the
+// current poll, or for an option that does not exist.
+func (machine *PollMachine) recordVote(vote vote) {
+	if vote.message.PollID != machine.poll.id {
+		machine.messenger.ReplyError(vote.clientID, MsgVote, "poll is not open")
+		return
+	}
+	if vote.message.Option < 0 || vote.message.Option >= len(machine.poll.options) {
+		machine.messenger.ReplyError(vote.clientID, MsgVote, "no such option")
+		return
+	}
+
+	machine.poll.votes[vote.clientID] = vote.message.Option
+	machine.publishPoll()
+}
+
+// Closes the current poll, and publishes its final tally.
+func (machine *PollMachine) closePoll() {
+	machine.poll.open = false
+	machine.publishPoll()
+}
+
+// Publishes the tally of the current poll to the retained results topic.
+func (machine *PollMachine) publishPoll() {
+	poll := machine.poll
+
+	options := make([]OptionTally, len(poll.options))
+	for i, option := range poll.options {
+		options[i].Option = option
+	}
+	for _, option := range poll.votes {
+		options[option].Votes++
+	}
+
+	message := PollMessage{
+		Message:  newMessage(MsgPoll),
+		PollID:   poll.id,
+		Question: poll.question,
+		Options:  options,
+		Votes:    len(poll.votes),
+		Open:     poll.open,
+		Creator:  poll.creator,
+	}
+	if poll.open {
+		message.Deadline = poll.deadline.UnixMilli()
+	}
+
+	machine.messenger.PublishJSON(ResultsTopic, message, true)
+}
@@ -0,0 +1,195 @@
+package poll
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	mqtt "github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/events"
+)
+
+// Poll-related constants for MQTT communication.
+const (
+	// Name of the MQTT topic where clients post to start or close a poll.
+	StatusTopic string = "coffeetalk/polls/status"
+
+	// Name of the MQTT topic where clients post their votes in the current poll.
+	// Votes are only received by the server, and not passed on to other clients.
+	VoteTopic string = "coffeetalk/polls/votes"
+
+	// Name of the MQTT topic where the server posts the live tally of the current poll, and the
+	// final tally when it closes. Retained so that clients joining at any time see the latest poll.
+	ResultsTopic string = "coffeetalk/polls/results"
+
+	// Prefix of the MQTT topics where the server replies to individual clients, followed by the
+	// client's ID. See ReplyTopic.
+	ReplyTopicPrefix string = "coffeetalk/polls/replies/"
+)
+
+// Version of the JSON message format on the poll topics.
+// Should be incremented when making breaking changes to the message types below.
+const MessageVersion = 1
+
+// Types of JSON messages sent on the poll topics.
+const (
+	MsgStartPoll string = "start-poll"
+	MsgClosePoll string = "close-poll"
+	MsgVote      string = "vote"
+	MsgPoll      string = "poll"
+	MsgError     string = room.MsgError
+)
+
+// Base struct to embed in all poll message types.
+type Message = room.Message
+
+// Message posted by clients on the status topic to start a poll.
+type StartPollMessage struct {
+	Message           // Type: MsgStartPoll
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+
+	// Number of seconds the poll is open for. Uses the machine's default duration if 0.
+	Duration int `json:"duration,omitempty"`
+}
+
+// Message posted by clients on the status topic to close the poll with the given ID before it
+// times out. Only the client that started the poll, or an admin, can close it.
+type ClosePollMessage struct {
+	Message     // Type: MsgClosePoll
+	PollID  int `json:"pollId"`
+}
+
+// Message posted by clients on the vote topic to vote in the poll with the given ID. Voting again
+// replaces the client's previous vote.
+type VoteMessage struct {
+	Message     // Type: MsgVote
+	PollID  int `json:"pollId"`
+	Option  int `json:"option"` // Index of the chosen option, starting at 0.
+}
+
+// Message posted by the server on the results topic with the tally of a poll, whenever a vote is
+// received, and when the poll opens and closes.
+type PollMessage struct {
+	Message                // Type: MsgPoll
+	PollID   int           `json:"pollId"`
+	Question string        `json:"question"`
+	Options  []OptionTally `json:"options"`
+	Votes    int           `json:"votes"` // Total number of votes.
+	Open     bool          `json:"open"`
+
+	// Client ID of the client that started the poll.
+	Creator string `json:"creator"`
+
+	// Unix timestamp (in milliseconds) of when the poll closes. Omitted once closed.
+	Deadline int64 `json:"deadline,omitempty"`
+}
+
+// The number of votes for one of a poll's options.
+type OptionTally struct {
+	Option string `json:"option"`
+	Votes  int    `json:"votes"`
+}
+
+// Message posted by the server on a client's reply topic when rejecting a message from the client,
+// such as MsgVote.
+type ErrorMessage = room.ErrorMessage
+
+// Returns the topic where the server replies to the client with the given ID.
+func ReplyTopic(clientID string) string {
+	return room.ReplyTopic(ReplyTopicPrefix, clientID)
+}
+
+// Returns a base message of the given type, with the current message version.
+func newMessage(messageType string) Message {
+	return Message{Version: MessageVersion, Type: messageType}
+}
+
+// Parses the base message of the given payload. Returns ok=false if the payload is not a JSON
+// message of the current message version.
+func parseMessage(payload []byte) (message Message, ok bool) {
+	err := json.Unmarshal(payload, &message)
+	if err != nil {
+		return Message{}, false
+	}
+
+	if message.Version != MessageVersion {
+		log.Printf("Unsupported poll message version: %v\n", message.Version)
+		return Message{}, false
+	}
+
+	return message, true
+}
+
+// Returns a handler for processing MQTT messages before they are passed on to subscribers.
+// Passes start and close messages on the poll status topic on to the given poll machine. Votes are
+// passed on to the machine, and rejected so that other clients do not see them. Messages are
+// dropped if the machine is not ready to receive them, so that the broker is never blocked.
+func (machine *PollMachine) MessageHandler() events.OnProcessMessage {
+	return func(client events.Client, packet events.Packet) (events.Packet, error) {
+		switch packet.TopicName {
+		case StatusTopic:
+			machine.handleStatusMessage(client, packet.Payload)
+		case VoteTopic:
+			machine.handleVote(client, packet.Payload)
+			return packet, mqtt.ErrRejectPacket
+		}
+
+		return packet, nil
+	}
+}
+
+// Handles the given payload from the poll status topic, passing start and close messages from the
+// given client on to the machine.
+func (machine *PollMachine) handleStatusMessage(client events.Client, payload []byte) {
+	message, ok := parseMessage(payload)
+	if !ok {
+		return
+	}
+
+	command := command{Command: machine.messenger.Command(client.ID, message.Type)}
+
+	switch message.Type {
+	case MsgStartPoll:
+		if json.Unmarshal(payload, &command.startOptions) != nil {
+			log.Printf("Invalid poll start from client ID %v\n", client.ID)
+			return
+		}
+
+		select {
+		case machine.start <- command:
+		default:
+			log.Println("Poll start dropped: poll machine busy")
+		}
+	case MsgClosePoll:
+		var closeMessage ClosePollMessage
+		if json.Unmarshal(payload, &closeMessage) != nil {
+			log.Printf("Invalid poll close from client ID %v\n", client.ID)
+			return
+		}
+		command.pollID = closeMessage.PollID
+
+		select {
+		case machine.commands <- command:
+		default:
+			log.Println("Poll close dropped: poll machine busy")
+		}
+	}
+}
+
+// Handles the given payload from the vote topic, passing valid votes from the given client on to
+// the machine.
+func (machine *PollMachine) handleVote(client events.Client, payload []byte) {
+	var message VoteMessage
+	err := json.Unmarshal(payload, &message)
+	if err != nil || message.Version != MessageVersion || message.Type != MsgVote {
+		log.Printf("Invalid poll vote from client ID %v\n", client.ID)
+		return
+	}
+
+	select {
+	case machine.votes <- vote{clientID: client.ID, message: message}:
+	default:
+		log.Printf("Poll vote from client ID %v dropped: poll machine busy\n", client.ID)
+	}
+}
@@ -0,0 +1,367 @@
+package quiz
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Registers the quiz HTTP API on the given mux:
+//   - GET /quiz/history returns completed quiz sessions, most recent first.
+//   - GET /quiz/leaderboard returns the all-time leaderboard across completed sessions.
+//   - GET /quiz/schedules returns the schedules for starting quizzes, with their next start time.
+//   - GET/POST /quiz/questions lists the question bank, or adds a question to it.
+//   - GET/PUT/DELETE /quiz/questions/{id} gets, replaces (e.g. to disable) or deletes a question.
+//   - POST /quiz/question-import adds a list of questions to the question bank (see
+//     QuestionBank.Import), responding with an ImportResult.
+//   - GET/DELETE /quiz/question-usage lists when questions were last asked in a room (the room
+//     query parameter, defaulting to the machine's room), or resets it so all count as unasked.
+//   - GET/PUT /quiz/media/{file} serves a question's media file, or uploads one (admin only).
+//
+// The question, question import and question usage endpoints reveal answers, so they require the
+// admin token as a bearer token in the Authorization header, and are disabled if no admin token is
+// configured.
+//
+// The history and leaderboard endpoints accept the query parameters room, since and until
+// (RFC 3339 or YYYY-MM-DD), and month (YYYY-MM) as a shorthand for since/until. History also
+// accepts limit.
+func (machine *QuizMachine) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/quiz/history", machine.handleHistory)
+	mux.HandleFunc("/quiz/leaderboard", machine.handleLeaderboard)
+	mux.HandleFunc("/quiz/schedules", machine.handleSchedules)
+	mux.HandleFunc("/quiz/questions", machine.handleQuestions)
+	mux.HandleFunc("/quiz/questions/", machine.handleQuestion)
+	mux.HandleFunc("/quiz/question-import", machine.handleQuestionImport)
+	mux.HandleFunc("/quiz/question-usage", machine.handleQuestionUsage)
+	mux.HandleFunc(mediaPath, machine.handleMedia)
+}
+
+// HTTP handler for querying the quiz history.
+func (machine *QuizMachine) handleHistory(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet) || !machine.checkStore(res) {
+		return
+	}
+
+	filter, err := parseSessionFilter(req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := machine.store.Sessions(filter)
+	if err != nil {
+		log.Println("Failed to read quiz history:", err)
+		http.Error(res, "failed to read quiz history", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(res, sessions)
+}
+
+// HTTP handler for querying the all-time leaderboard.
+func (machine *QuizMachine) handleLeaderboard(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet) || !machine.checkStore(res) {
+		return
+	}
+
+	filter, err := parseSessionFilter(req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leaderboard, err := machine.store.Leaderboard(filter)
+	if err != nil {
+		log.Println("Failed to read quiz leaderboard:", err)
+		http.Error(res, "failed to read quiz leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(res, leaderboard)
+}
+
+// HTTP handler for listing the questions in the question bank, or adding a question to it.
+func (machine *QuizMachine) handleQuestions(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet, http.MethodPost) || !machine.checkAdmin(res, req) {
+		return
+	}
+
+	if req.Method == http.MethodGet {
+		writeJSON(res, machine.questionBank.List())
+		return
+	}
+
+	question, ok := readQuestionBody(res, req)
+	if !ok {
+		return
+	}
+
+	question, err := machine.questionBank.Create(question)
+	if err != nil {
+		writeQuestionError(res, err)
+		return
+	}
+
+	writeJSONStatus(res, http.StatusCreated, question)
+}
+
+// HTTP handler for getting, replacing or deleting the question in the question bank with the ID
+// given in the URL path.
+func (machine *QuizMachine) handleQuestion(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet, http.MethodPut, http.MethodDelete) ||
+		!machine.checkAdmin(res, req) {
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/quiz/questions/"))
+	if err != nil {
+		http.Error(res, "invalid question ID", http.StatusBadRequest)
+		return
+	}
+
+	var question Question
+	switch req.Method {
+	case http.MethodGet:
+		question, err = machine.questionBank.Get(id)
+	case http.MethodPut:
+		var ok bool
+		question, ok = readQuestionBody(res, req)
+		if !ok {
+			return
+		}
+		question, err = machine.questionBank.Update(id, question)
+	case http.MethodDelete:
+		err = machine.questionBank.Delete(id)
+		if err == nil {
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if err != nil {
+		writeQuestionError(res, err)
+		return
+	}
+
+	writeJSON(res, question)
+}
+
+// Response of the question import endpoint.
+type ImportResult struct {
+	// Questions added to the question bank, with their assigned IDs.
+	Added []Question `json:"added"`
+
+	// Number of questions skipped for having the same text as one already in the bank.
+	Duplicates int `json:"duplicates"`
+}
+
+// HTTP handler for adding a list of questions to the question bank. Adds none of the questions if
+// any of them is invalid.
+func (machine *QuizMachine) handleQuestionImport(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodPost) || !machine.checkAdmin(res, req) {
+		return
+	}
+
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+
+	var questions []Question
+	err := decoder.Decode(&questions)
+	if err != nil {
+		http.Error(res, fmt.Sprintf("invalid questions: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	added, duplicates, err := machine.questionBank.Import(questions)
+	if err != nil {
+		if errors.Is(err, errSaveQuestionBank) {
+			writeQuestionError(res, err)
+		} else {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	writeJSON(res, ImportResult{Added: added, Duplicates: duplicates})
+}
+
+// HTTP handler for listing when questions were last asked in a room, or resetting it.
+func (machine *QuizMachine) handleQuestionUsage(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet, http.MethodDelete) ||
+		!machine.checkAdmin(res, req) ||
+		!machine.checkStore(res) {
+		return
+	}
+
+	room := req.URL.Query().Get("room")
+	if room == "" {
+		room = machine.room
+	}
+
+	if req.Method == http.MethodDelete {
+		err := machine.store.ResetQuestionUsage(room)
+		if err != nil {
+			log.Println("Failed to reset quiz question usage:", err)
+			http.Error(res, "failed to reset question usage", http.StatusInternalServerError)
+			return
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	usage, err := machine.store.QuestionUsage(room)
+	if err != nil {
+		log.Println("Failed to read quiz question usage:", err)
+		http.Error(res, "failed to read question usage", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(res, usage)
+}
+
+// Reads a question from the JSON body of the given request, rejecting unknown fields.
+// If invalid, responds with an error and returns ok=false.
+func readQuestionBody(res http.ResponseWriter, req *http.Request) (question Question, ok bool) {
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&question)
+	if err != nil {
+		http.Error(res, fmt.Sprintf("invalid question: %v", err), http.StatusBadRequest)
+		return Question{}, false
+	}
+
+	return question, true
+}
+
+// Responds with the given error from the question bank, using the status code matching the error.
+func writeQuestionError(res http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrQuestionNotFound):
+		http.Error(res, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrQuestionExists), errors.Is(err, ErrQuestionIDUsed):
+		http.Error(res, err.Error(), http.StatusConflict)
+	case errors.Is(err, errSaveQuestionBank):
+		log.Println(err)
+		http.Error(res, "failed to save question bank", http.StatusInternalServerError)
+	default:
+		http.Error(res, fmt.Sprintf("invalid question: %v", err), http.StatusBadRequest)
+	}
+}
+
+// Parses a session filter from the query parameters of the given request.
+// Returns error if a parameter is malformed.
+func parseSessionFilter(req *http.Request) (SessionFilter, error) {
+	query := req.URL.Query()
+	filter := SessionFilter{Room: query.Get("room")}
+
+	if month := query.Get("month"); month != "" {
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return SessionFilter{}, fmt.Errorf("invalid month '%v', expected YYYY-MM", month)
+		}
+		filter.Since = start
+		filter.Until = start.AddDate(0, 1, 0)
+	}
+
+	var err error
+	if since := query.Get("since"); since != "" {
+		filter.Since, err = parseTime(since)
+		if err != nil {
+			return SessionFilter{}, err
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		filter.Until, err = parseTime(until)
+		if err != nil {
+			return SessionFilter{}, err
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit < 0 {
+			return SessionFilter{}, fmt.Errorf("invalid limit '%v'", limit)
+		}
+	}
+
+	return filter, nil
+}
+
+// Parses the given time as either an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseTime(value string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+
+	parsed, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%v', expected RFC 3339 or YYYY-MM-DD", value)
+	}
+	return parsed, nil
+}
+
+// Checks that the machine has a store configured. If not, responds with an error and returns false.
+func (machine *QuizMachine) checkStore(res http.ResponseWriter) bool {
+	if machine.store == nil {
+		http.Error(res, "quiz history is not enabled", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// Checks that the given request has the machine's admin token as bearer token. If not, or if no
+// admin token is configured, responds with an error and returns false.
+func (machine *QuizMachine) checkAdmin(res http.ResponseWriter, req *http.Request) bool {
+	if machine.adminToken == "" {
+		http.Error(res, "quiz admin API is not enabled", http.StatusServiceUnavailable)
+		return false
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(machine.adminToken)) != 1 {
+		res.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(res, "invalid admin token", http.StatusUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+// Checks that the given request uses one of the given methods.
+// If not, responds with an error and returns false.
+func checkMethod(res http.ResponseWriter, req *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if req.Method == method {
+			return true
+		}
+	}
+
+	http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// Writes the given value to the response as JSON.
+func writeJSON(res http.ResponseWriter, value any) {
+	writeJSONStatus(res, http.StatusOK, value)
+}
+
+// Writes the given value to the response as JSON, with the given status code.
+func writeJSONStatus(res http.ResponseWriter, status int, value any) {
+	// Headers must be set before writing the status code, as they are sent with it.
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+
+	err := json.NewEncoder(res).Encode(value)
+	if err != nil {
+		log.Println("Failed to write JSON response:", err)
+	}
+}
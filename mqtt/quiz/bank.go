@@ -0,0 +1,349 @@
+package quiz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Bank of the questions that can be asked in quizzes, editable while the server is running.
+// Persisted to a JSON file, seeded with the embedded questions if the file does not exist.
+// Changes are only applied once saved, so that a failed save leaves the bank as it was.
+// Safe for concurrent use.
+type QuestionBank struct {
+	lock sync.RWMutex
+
+	// Path of the file the questions are persisted to. If empty, changes are kept in memory only.
+	path string
+
+	// The bank's questions, ordered by ID.
+	questions []Question
+
+	// ID to assign to the next question added without one. Never decreases, so that the IDs of
+	// deleted questions are not reused, as question usage and quiz history refer to questions by
+	// ID.
+	// Persisted next to the bank's file (see nextIDPath).
+	nextID int
+}
+
+// Errors returned by question bank operations.
+var (
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrQuestionExists   = errors.New("question with the same ID already exists")
+	ErrQuestionIDUsed   = errors.New("question ID was used by a deleted question")
+	errSaveQuestionBank = errors.New("failed to save question bank")
+)
+
+// Opens the question bank persisted at the given file path. If the file does not exist, creates it
+// with the embedded questions. Returns error if the file could not be read or written, or contains
+// invalid questions.
+func OpenQuestionBank(path string) (*QuestionBank, error) {
+	data, err := os.ReadFile(path)
+	seed := errors.Is(err, os.ErrNotExist)
+	if seed {
+		data = questionsJson
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read question bank: %w", err)
+	}
+
+	questions, err := readQuestions(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load question bank %v: %w", path, err)
+	}
+
+	sortQuestions(questions)
+	nextID, err := readNextID(nextIDPath(path))
+	if err != nil {
+		return nil, err
+	}
+	bank := &QuestionBank{path: path, questions: questions, nextID: maxNextID(questions, nextID)}
+
+	if seed {
+		err := bank.save(bank.questions, bank.nextID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bank, nil
+}
+
+// Returns a question bank with the embedded questions, kept in memory only.
+func defaultQuestionBank() *QuestionBank {
+	questions, err := readQuestions(questionsJson)
+	if err != nil {
+		// The embedded questions are part of the source, so this is a programming error.
+		panic(err)
+	}
+
+	sortQuestions(questions)
+	return &QuestionBank{questions: questions, nextID: maxNextID(questions, 1)}
+}
+
+// Returns all questions in the bank, including disabled ones, ordered by ID.
+func (bank *QuestionBank) List() []Question {
+	bank.lock.RLock()
+	defer bank.lock.RUnlock()
+
+	return append([]Question{}, bank.questions...)
+}
+
+// Returns the question with the given ID, or ErrQuestionNotFound.
+func (bank *QuestionBank) Get(id int) (Question, error) {
+	bank.lock.RLock()
+	defer bank.lock.RUnlock()
+
+	index, ok := bank.indexOf(id)
+	if !ok {
+		return Question{}, ErrQuestionNotFound
+	}
+	return bank.questions[index], nil
+}
+
+// Adds the given question to the bank, and returns it. Assigns it the next unused ID if its ID is
+// 0. Returns error if the question is invalid, its ID is taken or was used by a deleted question,
+// or saving the bank failed.
+func (bank *QuestionBank) Create(question Question) (Question, error) {
+	bank.lock.Lock()
+	defer bank.lock.Unlock()
+
+	if question.ID == 0 {
+		question.ID = bank.nextID
+	}
+
+	err := question.validate()
+	if err != nil {
+		return Question{}, err
+	}
+
+	if _, exists := bank.indexOf(question.ID); exists {
+		return Question{}, ErrQuestionExists
+	}
+	if question.ID < bank.nextID {
+		return Question{}, ErrQuestionIDUsed
+	}
+
+	questions := append(append([]Question{}, bank.questions...), question)
+	sortQuestions(questions)
+
+	err = bank.save(questions, maxNextID([]Question{question}, bank.nextID))
+	if err != nil {
+		return Question{}, err
+	}
+
+	return question, nil
+}
+
+// Replaces the question with the given ID by the given question, and returns it. Uses the given ID
+// if the question's ID is 0. Returns error if there is no question with the ID, the question is
+// invalid, its ID does not match the given ID, or saving the bank failed.
+func (bank *QuestionBank) Update(id int, question Question) (Question, error) {
+	bank.lock.Lock()
+	defer bank.lock.Unlock()
+
+	if question.ID == 0 {
+		question.ID = id
+	} else if question.ID != id {
+		return Question{}, fmt.Errorf("question ID %v does not match %v", question.ID, id)
+	}
+
+	err := question.validate()
+	if err != nil {
+		return Question{}, err
+	}
+
+	index, ok := bank.indexOf(id)
+	if !ok {
+		return Question{}, ErrQuestionNotFound
+	}
+
+	questions := append([]Question{}, bank.questions...)
+	questions[index] = question
+
+	err = bank.save(questions, bank.nextID)
+	if err != nil {
+		return Question{}, err
+	}
+
+	return question, nil
+}
+
+// Adds the given questions to the bank, assigning them the next unused IDs in order. Skips
+// questions with the same text as one already in the bank, or earlier in the list. Returns the
+// added questions, and the number of duplicates skipped. Returns error, adding none of the
+// questions, if any of them is invalid, or saving the bank failed.
+func (bank *QuestionBank) Import(
+	questions []Question,
+) (added []Question, duplicates int, err error) {
+	bank.lock.Lock()
+	defer bank.lock.Unlock()
+
+	texts := make(map[string]bool)
+	for _, question := range bank.questions {
+		texts[question.textKey()] = true
+	}
+
+	nextID := bank.nextID
+	added = make([]Question, 0, len(questions))
+	for i, question := range questions {
+		if texts[question.textKey()] {
+			duplicates++
+			continue
+		}
+		texts[question.textKey()] = true
+
+		question.ID = nextID
+		err := question.validate()
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid question %v (%v): %w", i+1, question.Question, err)
+		}
+
+		added = append(added, question)
+		nextID++
+	}
+
+	err = bank.save(append(append([]Question{}, bank.questions...), added...), nextID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return added, duplicates, nil
+}
+
+// Removes the question with the given ID from the bank.
+// Returns error if there is no question with the ID, or saving the bank failed.
+func (bank *QuestionBank) Delete(id int) error {
+	bank.lock.Lock()
+	defer bank.lock.Unlock()
+
+	index, ok := bank.indexOf(id)
+	if !ok {
+		return ErrQuestionNotFound
+	}
+	questions := append([]Question{}, bank.questions[:index]...)
+	questions = append(questions, bank.questions[index+1:]...)
+
+	return bank.save(questions, bank.nextID)
+}
+
+// Returns the questions in the bank that are not disabled.
+func (bank *QuestionBank) enabled() []Question {
+	bank.lock.RLock()
+	defer bank.lock.RUnlock()
+
+	enabled := make([]Question, 0, len(bank.questions))
+	for _, question := range bank.questions {
+		if !question.Disabled {
+			enabled = append(enabled, question)
+		}
+	}
+	return enabled
+}
+
+// Returns the index of the question with the given ID, or ok=false if there is none.
+// Expects the bank to be locked.
+func (bank *QuestionBank) indexOf(id int) (index int, ok bool) {
+	for i, question := range bank.questions {
+		if question.ID == id {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Sorts the given questions by ID.
+func sortQuestions(questions []Question) {
+	sort.Slice(questions, func(i int, j int) bool {
+		return questions[i].ID < questions[j].ID
+	})
+}
+
+// Returns the given next ID, or the ID after the highest of the given questions' IDs if greater.
+func maxNextID(questions []Question, nextID int) int {
+	for _, question := range questions {
+		if question.ID >= nextID {
+			nextID = question.ID + 1
+		}
+	}
+	return nextID
+}
+
+// Returns the path of the file that the next ID of the question bank at the given path is persisted
+// to.
+func nextIDPath(path string) string {
+	return path + ".next-id"
+}
+
+// Reads the next question ID persisted at the given path. Returns 1 if the file does not exist.
+func readNextID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 1, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read question bank next ID: %w", err)
+	}
+
+	nextID, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || nextID < 1 {
+		return 0, fmt.Errorf("invalid question bank next ID in %v: '%s'", path, data)
+	}
+	return nextID, nil
+}
+
+// Writes the given questions and next ID to the bank's files, if it has any, then replaces the
+// bank's questions and next ID with them. Leaves the bank unchanged if writing failed. The next ID
+// is written first, so that a failed write of the questions can never lead to reused IDs. Expects
+// the bank to be locked.
+func (bank *QuestionBank) save(questions []Question, nextID int) error {
+	if bank.path != "" {
+		data, err := json.MarshalIndent(questions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%w: %v", errSaveQuestionBank, err)
+		}
+
+		err = writeFileAtomic(nextIDPath(bank.path), []byte(strconv.Itoa(nextID)))
+		if err != nil {
+			return err
+		}
+
+		err = writeFileAtomic(bank.path, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	bank.questions = questions
+	bank.nextID = nextID
+	return nil
+}
+
+// Writes the given data and a trailing newline to the file at the given path. Writes to a
+// temporary file first, so that the file is never left half-written.
+func writeFileAtomic(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveQuestionBank, err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = tempFile.Write(append(data, '\n'))
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveQuestionBank, err)
+	}
+
+	err = os.Rename(tempFile.Name(), path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveQuestionBank, err)
+	}
+
+	return nil
+}
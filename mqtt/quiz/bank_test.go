@@ -0,0 +1,191 @@
+package quiz
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// Returns an empty question bank persisted in a temporary directory.
+func newTestBank(t *testing.T) *QuestionBank {
+	t.Helper()
+	return &QuestionBank{path: filepath.Join(t.TempDir(), "questions.json"), nextID: 1}
+}
+
+func TestQuestionBankIDs(t *testing.T) {
+	bank := newTestBank(t)
+
+	steps := []struct {
+		name    string
+		run     func() (Question, error)
+		wantID  int
+		wantErr error
+	}{
+		{"create", func() (Question, error) {
+			return bank.Create(Question{Question: "Q1", Answer: "A"})
+		}, 1, nil},
+		{"create", func() (Question, error) {
+			return bank.Create(Question{Question: "Q2", Answer: "A"})
+		}, 2, nil},
+		{"delete highest", func() (Question, error) {
+			return Question{}, bank.Delete(2)
+		}, 0, nil},
+		{"create after delete", func() (Question, error) {
+			return bank.Create(Question{Question: "Q3", Answer: "A"})
+		}, 3, nil},
+		{"create with deleted ID", func() (Question, error) {
+			return bank.Create(Question{ID: 2, Question: "Q4", Answer: "A"})
+		}, 0, ErrQuestionIDUsed},
+		{"create with taken ID", func() (Question, error) {
+			return bank.Create(Question{ID: 3, Question: "Q4", Answer: "A"})
+		}, 0, ErrQuestionExists},
+		{"create with unused ID", func() (Question, error) {
+			return bank.Create(Question{ID: 10, Question: "Q5", Answer: "A"})
+		}, 10, nil},
+		{"create after unused ID", func() (Question, error) {
+			return bank.Create(Question{Question: "Q6", Answer: "A"})
+		}, 11, nil},
+		{"update", func() (Question, error) {
+			return bank.Update(1, Question{Question: "Q1 updated", Answer: "B"})
+		}, 1, nil},
+		{"update mismatched ID", func() (Question, error) {
+			return bank.Update(1, Question{ID: 3, Question: "Q1", Answer: "B"})
+		}, 0, errAny},
+		{"update missing", func() (Question, error) {
+			return bank.Update(2, Question{Question: "Q2", Answer: "B"})
+		}, 0, ErrQuestionNotFound},
+		{"delete missing", func() (Question, error) {
+			return Question{}, bank.Delete(2)
+		}, 0, ErrQuestionNotFound},
+	}
+
+	for _, step := range steps {
+		question, err := step.run()
+		switch {
+		case step.wantErr == errAny && err == nil:
+			t.Errorf("%v: got no error, want error", step.name)
+		case step.wantErr != errAny && !errors.Is(err, step.wantErr):
+			t.Errorf("%v: got error %v, want %v", step.name, err, step.wantErr)
+		case err == nil && question.ID != step.wantID:
+			t.Errorf("%v: got ID %v, want %v", step.name, question.ID, step.wantID)
+		}
+	}
+
+	var ids []int
+	for _, question := range bank.List() {
+		ids = append(ids, question.ID)
+	}
+	if want := []int{1, 3, 10, 11}; !equalInts(ids, want) {
+		t.Errorf("List() IDs = %v, want %v", ids, want)
+	}
+
+	reopened, err := OpenQuestionBank(bank.path)
+	if err != nil {
+		t.Fatalf("OpenQuestionBank() failed: %v", err)
+	}
+	if len(reopened.List()) != 4 || reopened.nextID != 12 {
+		t.Errorf(
+			"reopened bank has %v questions and next ID %v, want 4 and 12",
+			len(reopened.List()), reopened.nextID,
+		)
+	}
+}
+
+func TestQuestionBankImport(t *testing.T) {
+	bank := newTestBank(t)
+	_, err := bank.Create(Question{Question: "Existing", Answer: "A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bank.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bank.Create(Question{Question: "Existing", Answer: "A"}); err != nil {
+		t.Fatal(err)
+	}
+
+	added, duplicates, err := bank.Import([]Question{
+		{Question: "existing", Answer: "A"},
+		{Question: "New", Answer: "A"},
+		{Question: "New", Answer: "B"},
+		{Question: "Other", Answer: "A"},
+	})
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	var ids []int
+	for _, question := range added {
+		ids = append(ids, question.ID)
+	}
+	if want := []int{3, 4}; !equalInts(ids, want) || duplicates != 2 {
+		t.Errorf("Import() added IDs %v with %v duplicates, want %v with 2", ids, duplicates, want)
+	}
+
+	_, _, err = bank.Import([]Question{{Question: "Valid", Answer: "A"}, {Question: "No answer"}})
+	if err == nil || len(bank.List()) != 3 {
+		t.Errorf("Import() with invalid question: got error %v and %v questions, want error and 3",
+			err, len(bank.List()))
+	}
+}
+
+func TestQuestionBankFailedSave(t *testing.T) {
+	// The bank's directory does not exist, so every save fails.
+	bank := &QuestionBank{
+		path:      filepath.Join(t.TempDir(), "missing", "questions.json"),
+		questions: []Question{{ID: 1, Question: "Q1", Answer: "A"}},
+		nextID:    2,
+	}
+
+	operations := []struct {
+		name string
+		run  func() error
+	}{
+		{"create", func() error {
+			_, err := bank.Create(Question{Question: "Q2", Answer: "A"})
+			return err
+		}},
+		{"update", func() error {
+			_, err := bank.Update(1, Question{Question: "Q1 updated", Answer: "A"})
+			return err
+		}},
+		{"import", func() error {
+			_, _, err := bank.Import([]Question{{Question: "Q3", Answer: "A"}})
+			return err
+		}},
+		{"delete", func() error {
+			return bank.Delete(1)
+		}},
+	}
+
+	for _, operation := range operations {
+		err := operation.run()
+		if !errors.Is(err, errSaveQuestionBank) {
+			t.Errorf("%v: got error %v, want %v", operation.name, err, errSaveQuestionBank)
+		}
+
+		questions := bank.List()
+		if len(questions) != 1 || questions[0].Question != "Q1" || bank.nextID != 2 {
+			t.Errorf(
+				"%v: bank changed after failed save: %v, next ID %v",
+				operation.name, questions, bank.nextID,
+			)
+		}
+	}
+}
+
+// Error expectation matching any non-nil error.
+var errAny = errors.New("any error")
+
+// Returns whether the given int slices have the same elements in the same order.
+func equalInts(first []int, second []int) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package quiz
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Configuration options for a quiz state machine.
+// Can be loaded from a JSON file with LoadConfig.
+type Config struct {
+	// Format of the payloads published on the quiz topics. Defaults to FormatJSON if empty.
+	PayloadFormat PayloadFormat `json:"payloadFormat"`
+
+	// Name of the quiz room served by the machine. Defaults to DefaultRoom if empty.
+	Room string `json:"room"`
+
+	// Persistent storage for completed quiz sessions. Optional; history is disabled if nil.
+	Store *Store `json:"-"`
+
+	// Bank of the questions to ask. Defaults to the embedded questions, kept in memory, if nil.
+	Questions *QuestionBank `json:"-"`
+
+	// Directory of the questions' media files, served by the HTTP API. Media is disabled if empty.
+	MediaDir string `json:"-"`
+
+	// Absolute base URL where clients can fetch the questions' media files, such as
+	// "https://example.com:1881/quiz/media/". Defaults to the HTTP API's media path, relative to
+	// the page, if empty, which only works if the page is served from the same origin as the API.
+	MediaURL string `json:"mediaUrl"`
+
+	// Bearer token required by the question bank HTTP API. The API is disabled if empty.
+	AdminToken string `json:"adminToken"`
+
+	// Rules for matching submitted answers. Defaults to DefaultMatchConfig if nil.
+	Matching *MatchConfig `json:"matching"`
+
+	// Name of the scoring strategy for sessions started without one. Defaults to ScoringClassic.
+	Scoring string `json:"scoring"`
+
+	// Name of the game mode for sessions started without one. Defaults to ModeTrivia.
+	Mode string `json:"mode"`
+
+	// Whether players can change their answer to a question after submitting it. Defaults to
+	// AllowAnswerChanges if empty.
+	AnswerPolicy AnswerPolicy `json:"answerPolicy"`
+
+	// Team mode for sessions started without one. Players compete individually if empty.
+	TeamMode TeamMode `json:"teamMode"`
+
+	// MQTT usernames of the quiz admins, allowed to control any quiz session, not just the ones
+	// they host.
+	Admins []string `json:"admins"`
+
+	// Language tag of the room's quiz sessions, such as "nb". Can be overridden when starting a
+	// quiz. Defaults to DefaultLanguage if empty.
+	Language string `json:"language"`
+
+	// What to do with questions lacking a translation to a session's language. Defaults to
+	// FallbackUntranslated if empty.
+	MissingTranslation TranslationPolicy `json:"missingTranslation"`
+
+	// Schedules for starting quizzes automatically. Only schedules for the machine's room apply.
+	Schedules []Schedule `json:"schedules"`
+}
+
+// Name of the quiz room used if none is configured.
+const DefaultRoom = "coffeetalk"
+
+// Reads a quiz config from the JSON file at the given path.
+// Returns error if the file could not be read, or the config is invalid.
+func LoadConfig(path string) (Config, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read quiz config: %w", err)
+	}
+
+	var config Config
+	err = json.Unmarshal(file, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse quiz config %v: %w", path, err)
+	}
+
+	switch config.PayloadFormat {
+	case "", FormatJSON, FormatText:
+	default:
+		return Config{}, fmt.Errorf(
+			"invalid payload format in quiz config: %v", config.PayloadFormat,
+		)
+	}
+
+	if _, ok := scorerByName(config.Scoring); config.Scoring != "" && !ok {
+		return Config{}, fmt.Errorf("unknown scoring strategy in quiz config: %v", config.Scoring)
+	}
+
+	for _, schedule := range config.Schedules {
+		if _, err := schedule.parse(); err != nil {
+			return Config{}, err
+		}
+
+		if _, ok := scorerByName(schedule.Scoring); schedule.Scoring != "" && !ok {
+			return Config{}, fmt.Errorf(
+				"unknown scoring strategy in quiz schedule '%v': %v",
+				schedule.Name,
+				schedule.Scoring,
+			)
+		}
+
+		if _, ok := gameModeByName(schedule.Mode); schedule.Mode != "" && !ok {
+			return Config{}, fmt.Errorf(
+				"unknown game mode in quiz schedule '%v': %v", schedule.Name, schedule.Mode,
+			)
+		}
+
+		if !schedule.TeamMode.valid() {
+			return Config{}, fmt.Errorf(
+				"unknown team mode in quiz schedule '%v': %v", schedule.Name, schedule.TeamMode,
+			)
+		}
+	}
+
+	if _, ok := gameModeByName(config.Mode); config.Mode != "" && !ok {
+		return Config{}, fmt.Errorf("unknown game mode in quiz config: %v", config.Mode)
+	}
+
+	if !config.MissingTranslation.valid() {
+		return Config{}, fmt.Errorf(
+			"invalid missing translation policy in quiz config: %v", config.MissingTranslation,
+		)
+	}
+
+	if !config.TeamMode.valid() {
+		return Config{}, fmt.Errorf("unknown team mode in quiz config: %v", config.TeamMode)
+	}
+
+	if !config.AnswerPolicy.valid() {
+		return Config{}, fmt.Errorf("unknown answer policy in quiz config: %v", config.AnswerPolicy)
+	}
+
+	return config, nil
+}
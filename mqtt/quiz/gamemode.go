@@ -0,0 +1,292 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A kind of game played in quiz sessions. Every game mode runs on the quiz machine's states, asking
+// a question, collecting answers, and revealing the outcome, over the same topics and with the same
+// start mechanism. Game modes decide which questions they can play, and how answers are graded.
+type GameMode interface {
+	// Returns error if the given question cannot be played in the game mode, for example if it is
+	// missing an answer or options that the mode requires.
+	Validate(question Question) error
+
+	// Grades the given answers to the given question, keyed by competitor, using the given matching
+	// rules. Returns the keys of the competitors whose answers count as correct.
+	Grade(question Question, answers map[string]string, matching MatchConfig) map[string]bool
+
+	// Returns the outcome of the given question to reveal to players, given the answers to it keyed
+	// by competitor.
+	Reveal(question Question, answers map[string]string, matching MatchConfig) Reveal
+
+	// Returns whether correct answers are awarded points by the session's scoring strategy.
+	Scored() bool
+}
+
+// The outcome of a question, as revealed to players after answering.
+type Reveal struct {
+	// The correct or winning answer.
+	Answer string
+
+	// Number of competitors giving each answer, with the most common first. Only set by game modes
+	// where the distribution of answers matters.
+	Tally []TallyEntry
+}
+
+// The number of competitors giving an answer to a question.
+type TallyEntry struct {
+	Answer string `json:"answer"`
+	Count  int    `json:"count"`
+}
+
+// Names of the game modes that can be picked when starting a quiz.
+const (
+	// Trivia questions with a correct answer.
+	ModeTrivia string = "trivia"
+
+	// Questions with a numeric answer, where the closest answers win.
+	ModeEstimation string = "estimation"
+
+	// Questions with options to choose between, where no option is correct, and the tally of
+	// choices is revealed.
+	ModeWouldYouRather string = "would-you-rather"
+
+	// A prompt word, where answers given by more than one competitor win.
+	ModeAssociation string = "association"
+)
+
+// Game modes that can be picked by name when starting a quiz.
+var gameModes = map[string]GameMode{
+	ModeTrivia:         TriviaMode{},
+	ModeEstimation:     EstimationMode{},
+	ModeWouldYouRather: WouldYouRatherMode{},
+	ModeAssociation:    AssociationMode{},
+}
+
+// Returns the game mode with the given name, or ok=false if there is none.
+func gameModeByName(name string) (mode GameMode, ok bool) {
+	mode, ok = gameModes[name]
+	return mode, ok
+}
+
+// Game mode for trivia questions, where answers matching the question's answer are correct.
+type TriviaMode struct{}
+
+// Returns error if the question has no answer.
+func (TriviaMode) Validate(question Question) error {
+	if strings.TrimSpace(question.Answer) == "" {
+		return errors.New("answer is required")
+	}
+	return nil
+}
+
+// Returns the competitors whose answers match the question's accepted answers. Answers to
+// multiple-choice questions must match exactly after normalization.
+func (TriviaMode) Grade(
+	question Question, answers map[string]string, matching MatchConfig,
+) map[string]bool {
+	correct := make(map[string]bool)
+	for key, answer := range answers {
+		if matching.Matches(question, answer) {
+			correct[key] = true
+		}
+	}
+	return correct
+}
+
+// Returns the question's answer.
+func (TriviaMode) Reveal(question Question, _ map[string]string, _ MatchConfig) Reveal {
+	return Reveal{Answer: question.Answer}
+}
+
+// Returns true, as trivia answers are scored.
+func (TriviaMode) Scored() bool {
+	return true
+}
+
+// Game mode for estimation questions with a numeric answer. The answers closest to the question's
+// answer are correct, as are answers within the question's tolerance.
+type EstimationMode struct{}
+
+// Returns error if the question's answer is not a number.
+func (EstimationMode) Validate(question Question) error {
+	if _, ok := parseNumber(DefaultMatchConfig.normalize(question.Answer)); !ok {
+		return fmt.Errorf("estimation answer '%v' is not a number", question.Answer)
+	}
+	return nil
+}
+
+// Returns the competitors whose answers are closest to the question's answer, or within tolerance
+// of it. Answers that are not numbers are never correct.
+func (EstimationMode) Grade(
+	question Question, answers map[string]string, matching MatchConfig,
+) map[string]bool {
+	correct := make(map[string]bool)
+
+	target, ok := parseNumber(matching.normalize(question.Answer))
+	if !ok {
+		return correct
+	}
+
+	distances := make(map[string]float64)
+	closest := math.Inf(1)
+	for key, answer := range answers {
+		number, ok := parseNumber(matching.normalize(answer))
+		if !ok {
+			continue
+		}
+
+		distances[key] = math.Abs(number - target)
+		if distances[key] < closest {
+			closest = distances[key]
+		}
+	}
+
+	tolerance := matching.NumericTolerance
+	if question.Tolerance > 0 {
+		tolerance = question.Tolerance
+	}
+
+	for key, distance := range distances {
+		if distance == closest || distance <= tolerance {
+			correct[key] = true
+		}
+	}
+	return correct
+}
+
+// Returns the question's answer.
+func (EstimationMode) Reveal(question Question, _ map[string]string, _ MatchConfig) Reveal {
+	return Reveal{Answer: question.Answer}
+}
+
+// Returns true, as the closest estimates are scored.
+func (EstimationMode) Scored() bool {
+	return true
+}
+
+// Game mode for "would you rather" questions, where players choose between the question's options.
+// No option is correct; the tally of choices is revealed.
+type WouldYouRatherMode struct{}
+
+// Returns error if the question has fewer than 2 options.
+func (WouldYouRatherMode) Validate(question Question) error {
+	if len(question.Options) < 2 {
+		return errors.New("at least 2 options are required")
+	}
+	return nil
+}
+
+// Returns no competitors, as no option is correct.
+func (WouldYouRatherMode) Grade(Question, map[string]string, MatchConfig) map[string]bool {
+	return make(map[string]bool)
+}
+
+// Returns the tally of the options chosen, and the most chosen option (or options, if tied) as the
+// answer. Answers not matching any option are not counted.
+func (WouldYouRatherMode) Reveal(
+	question Question, answers map[string]string, matching MatchConfig,
+) Reveal {
+	counts := make(map[string]int)
+	for _, option := range question.Options {
+		counts[option] = 0
+	}
+
+	for _, answer := range answers {
+		for _, option := range question.Options {
+			if matching.Matches(Question{Answer: option}, answer) {
+				counts[option]++
+				break
+			}
+		}
+	}
+
+	return tallyReveal(counts)
+}
+
+// Returns false, as no option is correct.
+func (WouldYouRatherMode) Scored() bool {
+	return false
+}
+
+// Game mode for word association, where players answer with the first word that comes to mind for
+// the question's prompt, and answers given by more than one competitor are correct.
+type AssociationMode struct{}
+
+// Accepts any question, as association prompts need no answer.
+func (AssociationMode) Validate(Question) error {
+	return nil
+}
+
+// Returns the competitors whose answers, after normalization, were also given by another
+// competitor. Answers that are empty after normalization never match, so that competitors cannot
+// score by submitting blank or punctuation-only answers.
+func (AssociationMode) Grade(
+	_ Question, answers map[string]string, matching MatchConfig,
+) map[string]bool {
+	counts := make(map[string]int)
+	for _, answer := range answers {
+		counts[matching.normalize(answer)]++
+	}
+
+	correct := make(map[string]bool)
+	for key, answer := range answers {
+		normalized := matching.normalize(answer)
+		if normalized != "" && counts[normalized] > 1 {
+			correct[key] = true
+		}
+	}
+	return correct
+}
+
+// Returns the tally of answers after normalization, and the most common answer (or answers, if
+// tied) as the answer.
+func (AssociationMode) Reveal(
+	_ Question, answers map[string]string, matching MatchConfig,
+) Reveal {
+	counts := make(map[string]int)
+	for _, answer := range answers {
+		normalized := matching.normalize(answer)
+		if normalized != "" {
+			counts[normalized]++
+		}
+	}
+
+	return tallyReveal(counts)
+}
+
+// Returns true, as matching associations are scored.
+func (AssociationMode) Scored() bool {
+	return true
+}
+
+// Returns a reveal with the given answer counts as tally, with the most common answer first, and
+// the most common answer (or answers, if tied) as the answer.
+func tallyReveal(counts map[string]int) Reveal {
+	tally := make([]TallyEntry, 0, len(counts))
+	for answer, count := range counts {
+		tally = append(tally, TallyEntry{Answer: answer, Count: count})
+	}
+
+	sort.Slice(tally, func(i int, j int) bool {
+		if tally[i].Count != tally[j].Count {
+			return tally[i].Count > tally[j].Count
+		}
+		return tally[i].Answer < tally[j].Answer
+	})
+
+	winners := make([]string, 0)
+	for _, entry := range tally {
+		if entry.Count == 0 || entry.Count < tally[0].Count {
+			break
+		}
+		winners = append(winners, entry.Answer)
+	}
+
+	return Reveal{Answer: strings.Join(winners, " / "), Tally: tally}
+}
@@ -0,0 +1,159 @@
+package quiz
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	"github.com/dcs-team4/coffeetalk/stm"
+	"github.com/mochi-co/mqtt/server/events"
+)
+
+// A start message or control command sent by an MQTT client on the quiz status topic, or a
+// scheduled start. Its kind is MsgStartQuiz, or one of the control commands.
+type command struct {
+	room.Command
+
+	// Options for the quiz session, if the command starts a quiz.
+	startOptions StartQuizMessage
+
+	// Receives whether the quiz was started, if the command starts a quiz and the channel is not
+	// nil. Should be buffered, as the machine does not wait for it to be received.
+	started chan<- bool
+}
+
+// Reports whether the command's quiz was started on its started channel, if it has one.
+func (command command) reportStarted(started bool) {
+	if command.started != nil {
+		command.started <- started
+	}
+}
+
+// Returns whether the given command may control the current quiz session: if it was sent by the
+// session's host or an admin. While the session has no host, only admins may control it, until a
+// participant takes over as host by answering. Otherwise, replies to the sender with an error.
+func (machine *QuizMachine) authorize(command command) bool {
+	if command.Admin || (machine.host != "" && command.ClientID == machine.host) {
+		return true
+	}
+
+	log.Printf(
+		"Rejecting quiz command '%v' from client ID %v: not host\n", command.Kind, command.ClientID,
+	)
+	machine.messenger.ReplyError(
+		command.ClientID, command.Kind, "only the quiz host can control the quiz",
+	)
+	return false
+}
+
+// Records the client with the given ID as a participant in the current quiz session, eligible to
+// take over as host. If the session has no host, such as when started by a schedule or when the
+// host left with no one to hand over to, the participant becomes host.
+func (machine *QuizMachine) addParticipant(currentState stm.StateID, clientID string) {
+	if machine.host == "" {
+		log.Printf("Client ID %v is now host of the quiz\n", clientID)
+		machine.host = clientID
+		machine.publishState(currentState)
+	}
+
+	for _, participant := range machine.participants {
+		if participant == clientID {
+			return
+		}
+	}
+
+	machine.participants = append(machine.participants, clientID)
+}
+
+// Removes the client with the given ID from the participants of the current quiz session. If the
+// client was the host, hands the host role over to the participant that joined first, or leaves
+// the session without a host if there are no participants left.
+func (machine *QuizMachine) handleDisconnect(currentState stm.StateID, clientID string) {
+	for i, participant := range machine.participants {
+		if participant == clientID {
+			machine.participants = append(machine.participants[:i], machine.participants[i+1:]...)
+			break
+		}
+	}
+
+	if currentState == idleState || clientID != machine.host {
+		return
+	}
+
+	machine.host = ""
+	for _, participant := range machine.participants {
+		if participant != clientID {
+			machine.host = participant
+			break
+		}
+	}
+
+	if machine.host == "" {
+		log.Printf("Quiz host (client ID %v) left, with no one to hand over to\n", clientID)
+	} else {
+		log.Printf(
+			"Quiz host (client ID %v) left, handing over to client ID %v\n", clientID, machine.host,
+		)
+	}
+
+	machine.publishState(currentState)
+}
+
+// Returns the IDs of the clients connected to the machine's broker and subscribed to the question
+// topic, who are expected to answer the quiz's questions.
+func (machine *QuizMachine) activeClients() []string {
+	active := make([]string, 0)
+	for clientID := range machine.broker.Topics.Subscribers(QuestionTopic) {
+		client, ok := machine.broker.Clients.Get(clientID)
+		if ok && atomic.LoadUint32(&client.State.Done) == 0 {
+			active = append(active, clientID)
+		}
+	}
+	return active
+}
+
+// Ends the current question early if every active client has answered it, by shortening the timer
+// to the minimum question duration, and publishes the new deadline. Only applies in the Question
+// state, while not paused.
+func (machine *QuizMachine) endQuestionIfAllAnswered(currentState stm.StateID) {
+	if currentState != questionState || machine.timer.paused {
+		return
+	}
+
+	question, err := machine.currentQuestion()
+	if err != nil {
+		return
+	}
+
+	active := machine.activeClients()
+	if len(active) == 0 {
+		return
+	}
+	for _, clientID := range active {
+		if !machine.standings.hasAnswered(clientID, question.ID) {
+			return
+		}
+	}
+
+	if machine.timer.shorten(minQuestionDuration - machine.timer.elapsed()) {
+		log.Printf(
+			"All %v active clients answered question %v, ending it early\n",
+			len(active),
+			question.ID,
+		)
+		machine.publishState(currentState)
+	}
+}
+
+// Returns a handler for clients disconnecting from the broker, letting the machine hand over the
+// host role if the host disconnects. Disconnects are dropped if the machine is not ready to receive
+// them, so that the broker is never blocked.
+func (machine *QuizMachine) DisconnectHandler() events.OnDisconnect {
+	return func(client events.Client, err error) {
+		select {
+		case machine.disconnects <- client.ID:
+		default:
+			log.Printf("Disconnect of client ID %v dropped: quiz machine busy\n", client.ID)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package quiz
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// A question in an Open Trivia DB (opentdb.com) response or dump, with the default encoding, where
+// text is HTML-escaped.
+type openTDBQuestion struct {
+	Category         string   `json:"category"`
+	Type             string   `json:"type"` // "multiple" or "boolean".
+	Difficulty       string   `json:"difficulty"`
+	Question         string   `json:"question"`
+	CorrectAnswer    string   `json:"correct_answer"`
+	IncorrectAnswers []string `json:"incorrect_answers"`
+}
+
+// Separator of list values, such as options, in CSV columns.
+const csvListSeparator = "|"
+
+// Converts the given Open Trivia DB JSON, either a full API response or a plain list of its
+// results, to questions without IDs. Multiple-choice and true/false questions keep their choices
+// as options, with the correct answer at a random position among the incorrect ones. Returns
+// error if the JSON is malformed.
+func ImportOpenTDB(data []byte) ([]Question, error) {
+	var response struct {
+		Results []openTDBQuestion `json:"results"`
+	}
+	err := json.Unmarshal(data, &response)
+	if err != nil {
+		// Dumps may also be plain lists of results.
+		err = json.Unmarshal(data, &response.Results)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Open Trivia DB questions: %w", err)
+		}
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	questions := make([]Question, 0, len(response.Results))
+	for _, result := range response.Results {
+		answer := html.UnescapeString(result.CorrectAnswer)
+
+		var options []string
+		if result.Type == "boolean" {
+			options = []string{"True", "False"}
+		} else if len(result.IncorrectAnswers) > 0 {
+			options = make([]string, 0, len(result.IncorrectAnswers)+1)
+			for _, incorrect := range result.IncorrectAnswers {
+				options = append(options, html.UnescapeString(incorrect))
+			}
+
+			position := random.Intn(len(options) + 1)
+			options = append(options[:position], append([]string{answer}, options[position:]...)...)
+		}
+
+		questions = append(questions, Question{
+			Question:   html.UnescapeString(result.Question),
+			Answer:     answer,
+			Options:    options,
+			Category:   html.UnescapeString(result.Category),
+			Difficulty: Difficulty(strings.ToLower(result.Difficulty)),
+		})
+	}
+
+	return questions, nil
+}
+
+// Converts the CSV from the given reader to questions without IDs. Expects a header row naming the
+// columns, in any order: question (required), answer, options, alternatives, category,
+// difficulty, mode and language. Options and alternatives are separated by "|". Returns error if
+// the CSV is malformed, has unknown columns, or has a trivia question with options that do not
+// include its answer (which would make the question impossible to answer correctly).
+func ImportCSV(reader io.Reader) ([]Question, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "question", "answer", "options", "alternatives", "category", "difficulty", "mode",
+			"language":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown CSV column '%v'", name)
+		}
+	}
+	if _, ok := columns["question"]; !ok {
+		return nil, errors.New("CSV header has no question column")
+	}
+
+	questions := make([]Question, 0)
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		question := Question{
+			Question:     field("question"),
+			Answer:       field("answer"),
+			Mode:         field("mode"),
+			Options:      splitCSVList(field("options")),
+			Alternatives: splitCSVList(field("alternatives")),
+			Category:     field("category"),
+			Difficulty:   Difficulty(strings.ToLower(field("difficulty"))),
+			Language:     field("language"),
+		}
+		if !question.answerInOptions() {
+			return nil, fmt.Errorf(
+				"CSV question %v (%v): answer must be one of the options",
+				len(questions)+1,
+				question.Question,
+			)
+		}
+
+		questions = append(questions, question)
+	}
+
+	return questions, nil
+}
+
+// Returns false if the question is a trivia question with multiple-choice options, and its answer
+// is not among them.
+func (question Question) answerInOptions() bool {
+	if question.mode() != ModeTrivia || len(question.Options) == 0 {
+		return true
+	}
+
+	for _, option := range question.Options {
+		if strings.EqualFold(strings.TrimSpace(option), strings.TrimSpace(question.Answer)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the given CSV list value on csvListSeparator, trimming space around each value. Returns
+// nil if the value is empty.
+func splitCSVList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	values := strings.Split(value, csvListSeparator)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return values
+}
@@ -0,0 +1,122 @@
+package quiz
+
+import (
+	"errors"
+	"strings"
+)
+
+// Language of questions that do not specify one, and of quiz sessions if none is configured.
+const DefaultLanguage = "en"
+
+// A question's text and accepted answers in another language than the question's own.
+type Translation struct {
+	Question     string   `json:"question"`
+	Answer       string   `json:"answer,omitempty"`
+	Alternatives []string `json:"alternatives,omitempty"`
+	Options      []string `json:"options,omitempty"`
+}
+
+// What to do with questions that lack a translation to the quiz session's language.
+type TranslationPolicy string
+
+const (
+	// Asks untranslated questions in their own language.
+	FallbackUntranslated TranslationPolicy = "fallback"
+
+	// Does not ask untranslated questions.
+	SkipUntranslated TranslationPolicy = "skip"
+)
+
+// Returns whether the translation policy is one of the supported policies, or empty.
+func (policy TranslationPolicy) valid() bool {
+	switch policy {
+	case "", FallbackUntranslated, SkipUntranslated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the given language tag in lower case without surrounding whitespace, so that tags can be
+// compared case-insensitively.
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// Returns the language of the question's own text and answers.
+func (question Question) language() string {
+	if question.Language == "" {
+		return DefaultLanguage
+	}
+	return normalizeLanguage(question.Language)
+}
+
+// Returns the question translated to the given language, with its language set accordingly.
+// Returns the question as is if it is already in the language. Returns ok=false if the question
+// has no translation to the language.
+func (question Question) localize(language string) (localized Question, ok bool) {
+	language = normalizeLanguage(language)
+	if language == question.language() {
+		return question, true
+	}
+
+	for translationLanguage, translation := range question.Translations {
+		if normalizeLanguage(translationLanguage) != language {
+			continue
+		}
+
+		localized = question
+		localized.Question = translation.Question
+		localized.Answer = translation.Answer
+		localized.Alternatives = translation.Alternatives
+		if len(translation.Options) > 0 {
+			localized.Options = translation.Options
+		}
+		localized.Language = language
+		localized.Translations = nil
+		return localized, true
+	}
+
+	return question, false
+}
+
+// Returns the given questions translated to the given language. Questions without a translation
+// are kept in their own language, or left out if the given policy is SkipUntranslated.
+func localizeQuestions(
+	questions []Question, language string, policy TranslationPolicy,
+) []Question {
+	localized := make([]Question, 0, len(questions))
+	for _, question := range questions {
+		translated, ok := question.localize(language)
+		if !ok && policy == SkipUntranslated {
+			continue
+		}
+
+		localized = append(localized, translated)
+	}
+	return localized
+}
+
+// Returns error if the translation is missing required fields, or fields that the given translated
+// question has.
+func (translation Translation) validate(question Question) error {
+	if strings.TrimSpace(translation.Question) == "" {
+		return errors.New("question text is required")
+	}
+
+	if question.Answer != "" && strings.TrimSpace(translation.Answer) == "" {
+		return errors.New("answer is required")
+	}
+
+	if len(translation.Options) > 0 && len(translation.Options) != len(question.Options) {
+		return errors.New("options must match the question's options")
+	}
+
+	for _, alternative := range translation.Alternatives {
+		if strings.TrimSpace(alternative) == "" {
+			return errors.New("alternative answers cannot be empty")
+		}
+	}
+
+	return nil
+}
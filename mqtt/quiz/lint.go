@@ -0,0 +1,114 @@
+package quiz
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity of a problem found in a list of questions.
+type LintSeverity string
+
+const (
+	// The questions cannot be loaded by the quiz server.
+	LintError LintSeverity = "error"
+
+	// The questions can be loaded, but likely contain a mistake.
+	LintWarning LintSeverity = "warning"
+)
+
+// A problem found in a list of questions by LintQuestions.
+type LintIssue struct {
+	Severity LintSeverity
+
+	// Position of the question in the list, starting at 1. 0 if the issue is not about a single
+	// question.
+	Position int
+
+	// ID of the question. 0 if the issue is not about a single question.
+	QuestionID int
+
+	Message string
+}
+
+// Returns the issue as a single line, such as "error: question 3 (ID 4): answer is required".
+func (issue LintIssue) String() string {
+	if issue.Position == 0 {
+		return fmt.Sprintf("%v: %v", issue.Severity, issue.Message)
+	}
+
+	return fmt.Sprintf(
+		"%v: question %v (ID %v): %v",
+		issue.Severity, issue.Position, issue.QuestionID, issue.Message,
+	)
+}
+
+// Checks the given JSON list of questions, and returns all problems found, rather than stopping
+// at the first one like readQuestions. Reports errors for everything that readQuestions rejects:
+// malformed JSON, invalid questions (including their answers, options and translations), and
+// duplicate IDs. Reports warnings for duplicate question text, and for game modes with fewer
+// enabled questions than a quiz session asks.
+func LintQuestions(questionsJson []byte) []LintIssue {
+	questions, err := parseQuestions(questionsJson)
+	if err != nil {
+		return []LintIssue{{Severity: LintError, Message: err.Error()}}
+	}
+
+	issues := make([]LintIssue, 0)
+	addIssue := func(severity LintSeverity, index int, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity:   severity,
+			Position:   index + 1,
+			QuestionID: questions[index].ID,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	ids := make(map[int]bool)
+	texts := make(map[string]int)
+	modeCounts := make(map[string]int)
+	for i, question := range questions {
+		err := question.validate()
+		if err != nil {
+			addIssue(LintError, i, "%v", err)
+		}
+
+		if ids[question.ID] {
+			addIssue(LintError, i, "duplicate question ID %v", question.ID)
+		}
+		ids[question.ID] = true
+
+		if first, ok := texts[question.textKey()]; ok {
+			addIssue(LintWarning, i, "same question text as question %v", first+1)
+		} else {
+			texts[question.textKey()] = i
+		}
+
+		if !question.Disabled {
+			modeCounts[question.mode()]++
+		}
+	}
+
+	if len(modeCounts) == 0 {
+		issues = append(issues, LintIssue{Severity: LintWarning, Message: "no enabled questions"})
+	}
+
+	modes := make([]string, 0, len(modeCounts))
+	for mode := range modeCounts {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+
+	for _, mode := range modes {
+		if modeCounts[mode] < maxQuestionCount {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Message: fmt.Sprintf(
+					"only %v enabled %v questions, fewer than the %v asked in a quiz",
+					modeCounts[mode], mode, maxQuestionCount,
+				),
+			})
+		}
+	}
+
+	return issues
+}
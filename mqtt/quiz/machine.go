@@ -3,9 +3,14 @@ package quiz
 import (
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
 	"github.com/dcs-team4/coffeetalk/stm"
 	mqtt "github.com/mochi-co/mqtt/server"
+	"github.com/robfig/cron/v3"
 )
 
 // State machine for quiz sessions.
@@ -14,22 +19,133 @@ type QuizMachine struct {
 	// Map of quiz machine state IDs to the functions that should run for those states.
 	states stm.States[*QuizMachine]
 
-	// Triggered to start a new quiz session.
-	start stm.Event
+	// Receives start messages to start a new quiz session.
+	start chan command
 
-	// Triggered when the time between question and answer has run out.
-	questionTimer stm.Event
+	// Receives control commands (pause, resume, skip, abort) for a running quiz session.
+	commands chan command
 
-	// Triggered when the time between an answer and the next question has run out.
-	answerTimer stm.Event
+	// Receives the IDs of clients that disconnect from the broker.
+	disconnects chan string
+
+	// Receives answers submitted by clients to the current question.
+	submissions chan submission
+
+	// Expires when the time between question and answer, or between an answer and the next
+	// question, has run out.
+	timer *quizTimer
 
 	// List of questions asked so far in the current quiz session.
 	questions []Question
 
+	// The number of questions to ask in the current quiz session.
+	questionCount int
+
+	// Language tag of the questions in the current quiz session.
+	language string
+
+	// Scores and answers of the players in the current quiz session.
+	standings *standings
+
+	// When the current quiz session started.
+	startedAt time.Time
+
+	// Name of the scoring strategy for the current quiz session.
+	scoring string
+
+	// Scoring strategy for the current quiz session.
+	scorer Scorer
+
+	// Name of the game mode of the current quiz session.
+	mode string
+
+	// Game mode of the current quiz session.
+	gameMode GameMode
+
+	// Outcome of the current question, once graded in the Answer state.
+	reveal Reveal
+
+	// Client ID of the current quiz session's host: the client that started it, or the participant
+	// it was handed over to. Empty if there is no host.
+	host string
+
+	// IDs of the clients that have answered in the current quiz session and are still connected,
+	// in the order they first answered.
+	participants []string
+
+	// How answers are combined per team in the current quiz session. TeamsOff if not in team mode.
+	teamMode TeamMode
+
 	// The MQTT broker where questions and answers are to be published.
 	broker *mqtt.Server
+
+	// Format of the payloads published on the quiz topics.
+	payloadFormat PayloadFormat
+
+	// Name of the quiz room served by the machine, recorded in the quiz history.
+	room string
+
+	// Persistent storage for the quiz history. Nil if history is disabled.
+	store *Store
+
+	// Rules for matching submitted answers against the accepted answers of questions.
+	matching MatchConfig
+
+	// Name of the scoring strategy used for sessions started without one.
+	defaultScoring string
+
+	// Name of the game mode used for sessions started without one.
+	defaultMode string
+
+	// Whether players can change their answer to a question after submitting it.
+	answerPolicy AnswerPolicy
+
+	// Team mode used for sessions started without one.
+	defaultTeamMode TeamMode
+
+	// Publishes the machine's JSON messages, and identifies the quiz admins, allowed to control any
+	// quiz session.
+	messenger *room.Messenger
+
+	// Starts quiz sessions automatically according to the machine's schedules.
+	scheduler *cron.Cron
+
+	// Schedules for starting quiz sessions in the machine's room.
+	schedules []scheduledQuiz
+
+	// Bank of the questions that can be asked in quiz sessions.
+	questionBank *QuestionBank
+
+	// Bearer token required by the question bank HTTP API. The API is disabled if empty.
+	adminToken string
+
+	// Language of quiz sessions started without one.
+	defaultLanguage string
+
+	// What to do with questions lacking a translation to the quiz session's language.
+	translationPolicy TranslationPolicy
+
+	// Directory of the questions' media files. Media is disabled if empty.
+	mediaDir string
+
+	// Base URL of the questions' media files, to which media file names are appended.
+	mediaURL string
 }
 
+// An answer submitted by an MQTT client.
+type submission struct {
+	clientID string
+	message  SubmitAnswerMessage
+
+	// When the broker received the answer, to tell answers sent before the question's deadline
+	// from late ones.
+	receivedAt time.Time
+}
+
+// Capacity of the machine's submission and disconnect channels. Buffered, so that answers or
+// disconnects from many clients arriving at once are not dropped.
+const submissionBufferSize = 64
+
 // IDs of the quiz machine's states.
 // Uses iota for automatic enumeration, +1 to avoid potential clash with zero value.
 const (
@@ -40,19 +156,77 @@ const (
 
 // Returns a new quiz state machine, with all states, channels and lists initialized.
 // Attaches the given broker to the machine, and assumes it is valid to send on.
-func NewMachine(broker *mqtt.Server) *QuizMachine {
-	return &QuizMachine{
+func NewMachine(broker *mqtt.Server, config Config) *QuizMachine {
+	if config.PayloadFormat == "" {
+		config.PayloadFormat = FormatJSON
+	}
+	if config.Room == "" {
+		config.Room = DefaultRoom
+	}
+	if config.Matching == nil {
+		config.Matching = &DefaultMatchConfig
+	}
+	if config.Scoring == "" {
+		config.Scoring = ScoringClassic
+	}
+	if config.Mode == "" {
+		config.Mode = ModeTrivia
+	}
+	if config.AnswerPolicy == "" {
+		config.AnswerPolicy = AllowAnswerChanges
+	}
+	if config.Questions == nil {
+		config.Questions = defaultQuestionBank()
+	}
+	if config.Language == "" {
+		config.Language = DefaultLanguage
+	}
+	if config.MissingTranslation == "" {
+		config.MissingTranslation = FallbackUntranslated
+	}
+	if config.MediaURL == "" {
+		config.MediaURL = mediaPath
+	} else if !strings.HasSuffix(config.MediaURL, "/") {
+		config.MediaURL += "/"
+	}
+
+	messenger := room.NewMessenger(broker, "quiz", MessageVersion, ReplyTopicPrefix, config.Admins)
+
+	machine := &QuizMachine{
 		states: stm.States[*QuizMachine]{
 			idleState:     runIdleState,
 			questionState: runQuestionState,
 			answerState:   runAnswerState,
 		},
-		start:         make(stm.Event),
-		questionTimer: make(stm.Event),
-		answerTimer:   make(stm.Event),
-		questions:     make([]Question, 0),
-		broker:        broker,
+		start:             make(chan command),
+		commands:          make(chan command),
+		disconnects:       make(chan string, submissionBufferSize),
+		submissions:       make(chan submission, submissionBufferSize),
+		timer:             newQuizTimer(),
+		questions:         make([]Question, 0),
+		standings:         newStandings(TeamsOff),
+		broker:            broker,
+		payloadFormat:     config.PayloadFormat,
+		room:              config.Room,
+		store:             config.Store,
+		matching:          *config.Matching,
+		defaultScoring:    config.Scoring,
+		defaultMode:       config.Mode,
+		answerPolicy:      config.AnswerPolicy,
+		defaultTeamMode:   config.TeamMode,
+		messenger:         messenger,
+		scheduler:         cron.New(),
+		schedules:         make([]scheduledQuiz, 0),
+		questionBank:      config.Questions,
+		adminToken:        config.AdminToken,
+		defaultLanguage:   normalizeLanguage(config.Language),
+		translationPolicy: config.MissingTranslation,
+		mediaDir:          config.MediaDir,
+		mediaURL:          config.MediaURL,
 	}
+	machine.addSchedules(config.Schedules)
+
+	return machine
 }
 
 // Returns the quiz machine's configured states.
@@ -63,6 +237,18 @@ func (machine *QuizMachine) States() stm.States[*QuizMachine] {
 // Runs the given quiz state machine. Keeps running through every configured state function,
 // transitioning to new states as they return, until an error occurs.
 func (machine *QuizMachine) Run() error {
+	// Replaces any stale quiz content retained by the broker from before the machine started, such
+	// as a quiz interrupted by a restart of a persistent broker. The results and leaderboard of the
+	// last completed quiz are kept.
+	if len(machine.broker.Topics.Messages(QuizStatusTopic)) != 0 {
+		machine.publish(QuizStatusTopic, StatusMessage{newMessage(MsgEndQuiz)}, true)
+	}
+	machine.clearRetained()
+	machine.publishState(idleState)
+
+	machine.scheduler.Start()
+	defer machine.scheduler.Stop()
+
 	startState := idleState
 	err := stm.RunMachine(machine, startState)
 	return err
@@ -77,30 +263,55 @@ func (machine QuizMachine) currentQuestion() (Question, error) {
 	return machine.questions[len(machine.questions)-1], nil
 }
 
-// Waits for a Start event to trigger, then returns the Question state as the next state.
+// Waits for a Start event to trigger, then starts a new quiz session and returns the Question state
+// as the next state. Ignores control commands and answers, as there is no quiz session.
 func runIdleState(machine *QuizMachine) (nextState stm.StateID, err error) {
-	<-machine.start
-	return questionState, nil
+	for {
+		select {
+		case command := <-machine.start:
+			started := machine.startQuiz(command)
+			command.reportStarted(started)
+			if started {
+				return questionState, nil
+			}
+		case command := <-machine.commands:
+			log.Printf("Ignoring quiz command '%v': no quiz in progress\n", command.Kind)
+			machine.messenger.ReplyError(command.ClientID, command.Kind, "no quiz in progress")
+		case <-machine.disconnects:
+		case submission := <-machine.submissions:
+			log.Printf(
+				"Ignoring answer from client ID %v: no quiz in progress\n", submission.clientID,
+			)
+		}
+	}
 }
 
 // Adds a new question to the machine's questions list, publishes it to the MQTT broker,
-// then waits for the question timer event before transitioning to the Answer state.
+// then waits for the question timer to expire before transitioning to the Answer state.
 func runQuestionState(machine *QuizMachine) (nextState stm.StateID, err error) {
-	question, err := newQuestion(machine.questions)
+	question, err := newQuestion(
+		machine.askableQuestions(), machine.questions, machine.questionUsage(),
+	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get new quiz question: %w", err)
+		// Questions may have been disabled or deleted since the quiz started, so ends the quiz
+		// early rather than failing.
+		log.Println("Ending quiz early: failed to get new quiz question:", err)
+		if len(machine.questions) > 0 {
+			machine.publishResults()
+		}
+		machine.endQuiz()
+		return idleState, nil
 	}
 	machine.questions = append(machine.questions, question)
+	machine.recordQuestionAsked(question)
 
-	machine.broker.Publish(QuestionTopic, []byte(question.Question), true)
+	machine.timer.start(questionDuration)
+	machine.publishQuestion(question)
 
-	go stm.SetTimer(questionDuration, machine.questionTimer)
-	<-machine.questionTimer
-
-	return answerState, nil
+	return machine.waitForTimer(questionState, answerState)
 }
 
-// Publishes the answer to the previous quiz question, and waits for the answer timer event.
+// Publishes the answer to the previous quiz question, and waits for the answer timer to expire.
 // Then, if the quiz has reached its final question, ends the quiz and returns to the Idle state;
 // otherwise, transitions to a new Question state.
 func runAnswerState(machine *QuizMachine) (nextState stm.StateID, err error) {
@@ -109,17 +320,379 @@ func runAnswerState(machine *QuizMachine) (nextState stm.StateID, err error) {
 		return 0, fmt.Errorf("quiz machine answer state failed: %w", err)
 	}
 
-	machine.broker.Publish(AnswerTopic, []byte(question.Answer), true)
+	// Grades answers before publishing the answer, so that the published state includes the scores.
+	machine.reveal = machine.standings.grade(
+		question, machine.gameMode, machine.matching, machine.scorer, questionDuration,
+	)
+
+	machine.timer.start(answerDuration)
+	machine.publishAnswer(question)
+	machine.messenger.PublishJSON(LeaderboardTopic, LeaderboardMessage{
+		Message:   newMessage(MsgLeaderboard),
+		Index:     len(machine.questions),
+		Total:     machine.questionCount,
+		TeamMode:  machine.teamMode,
+		Standings: machine.standings.ranked(),
+	}, true)
 
-	go stm.SetTimer(answerDuration, machine.answerTimer)
-	<-machine.answerTimer
+	nextState, err = machine.waitForTimer(answerState, questionState)
+	if nextState == idleState || err != nil {
+		return nextState, err
+	}
 
-	// If this is the final question, end the quiz and clean up the questions.
-	if len(machine.questions) >= maxQuestionCount {
-		machine.broker.Publish(QuizStatusTopic, []byte(QuizEndMessage), true)
-		machine.questions = make([]Question, 0)
+	// If this is the final question, publish and save the results, and end the quiz.
+	if len(machine.questions) >= machine.questionCount {
+		machine.publishResults()
+		machine.endQuiz()
 		return idleState, nil
 	}
 
-	return questionState, nil
+	return nextState, nil
+}
+
+// Waits for the machine's timer to expire, then returns the given next state.
+// While waiting, handles control commands for the quiz session in the given current state:
+//   - Pause stops the timer, preserving its remaining time.
+//   - Resume restarts a paused timer, and republishes the current question or answer with the
+//     updated deadline.
+//   - Skip moves on to the next state right away.
+//   - Abort ends the quiz, and returns the Idle state.
+//
+// Commands are only carried out if sent by the session's host or an admin. If the host disconnects,
+// the host role is handed over to another participant, or to the next client to answer if there is
+// none. Answers submitted to the current question are recorded while in the Question state.
+func (machine *QuizMachine) waitForTimer(
+	currentState stm.StateID, nextState stm.StateID,
+) (stm.StateID, error) {
+	for {
+		select {
+		case <-machine.timer.expired():
+			if currentState == questionState {
+				machine.drainSubmissions(currentState)
+			}
+			return nextState, nil
+		case command := <-machine.start:
+			log.Println("Ignoring quiz start: quiz already in progress")
+			machine.messenger.ReplyError(command.ClientID, command.Kind, "quiz already in progress")
+			command.reportStarted(false)
+		case submission := <-machine.submissions:
+			machine.handleAnswer(currentState, submission)
+		case clientID := <-machine.disconnects:
+			machine.handleDisconnect(currentState, clientID)
+			machine.endQuestionIfAllAnswered(currentState)
+		case command := <-machine.commands:
+			if !machine.authorize(command) {
+				continue
+			}
+
+			switch command.Kind {
+			case MsgPauseQuiz:
+				if machine.timer.pause() {
+					machine.publish(QuizStatusTopic, StatusMessage{newMessage(MsgPauseQuiz)}, false)
+					machine.publishState(currentState)
+				}
+			case MsgResumeQuiz:
+				if machine.timer.resume() {
+					err := machine.republish(currentState)
+					if err != nil {
+						return 0, err
+					}
+				}
+			case MsgSkipQuestion:
+				machine.timer.stop()
+				return nextState, nil
+			case MsgAbortQuiz:
+				machine.timer.stop()
+				machine.endQuiz()
+				return idleState, nil
+			}
+		}
+	}
+}
+
+// Records the given submitted answer in the standings, if it answers the current question while in
+// the Question state, the quiz is not paused, and it was received before the question's deadline.
+// Replies to the submitter with an acknowledgement if the answer was recorded, or with an error if
+// it was rejected, such as for arriving late, or changing a locked-in answer.
+func (machine *QuizMachine) handleAnswer(currentState stm.StateID, submission submission) {
+	question, err := machine.currentQuestion()
+	if err != nil || currentState != questionState || machine.timer.paused ||
+		submission.message.QuestionID != question.ID ||
+		submission.receivedAt.After(machine.timer.deadline) {
+		log.Printf(
+			"Rejecting answer from client ID %v: question %v is not open for answers\n",
+			submission.clientID,
+			submission.message.QuestionID,
+		)
+		machine.messenger.ReplyError(
+			submission.clientID, MsgSubmitAnswer, "question is not open for answers",
+		)
+		return
+	}
+
+	changed, err := machine.standings.recordAnswer(
+		submission.clientID,
+		submission.message.Name,
+		submission.message.Team,
+		question.ID,
+		submission.message.Answer,
+		machine.timer.elapsed(),
+		machine.answerPolicy != LockFirstAnswer,
+	)
+	if err != nil {
+		log.Printf("Rejecting answer from client ID %v: %v\n", submission.clientID, err)
+		machine.messenger.ReplyError(submission.clientID, MsgSubmitAnswer, err.Error())
+		return
+	}
+
+	machine.addParticipant(currentState, submission.clientID)
+	machine.replyAnswerReceived(submission, changed)
+	machine.endQuestionIfAllAnswered(currentState)
+}
+
+// Publishes the final results of the quiz session, and saves the session to the quiz history if
+// the machine has a store.
+func (machine *QuizMachine) publishResults() {
+	standings := machine.standings.ranked()
+	questions := machine.standings.recap(machine.questions)
+
+	machine.messenger.PublishJSON(ResultsTopic, ResultsMessage{
+		Message:   newMessage(MsgResults),
+		Scoring:   machine.scoring,
+		TeamMode:  machine.teamMode,
+		Mode:      machine.mode,
+		Standings: standings,
+		Questions: questions,
+	}, true)
+
+	if machine.store == nil {
+		return
+	}
+
+	err := machine.store.SaveSession(Session{
+		Room:      machine.room,
+		Scoring:   machine.scoring,
+		TeamMode:  machine.teamMode,
+		Mode:      machine.mode,
+		Language:  machine.language,
+		StartedAt: machine.startedAt,
+		EndedAt:   time.Now(),
+		Standings: standings,
+		Questions: questions,
+	})
+	if err != nil {
+		log.Println("Failed to save quiz session to history:", err)
+	}
+}
+
+// Publishes the current question or answer, depending on the given state, with the deadline of the
+// machine's current timer.
+func (machine *QuizMachine) republish(currentState stm.StateID) error {
+	question, err := machine.currentQuestion()
+	if err != nil {
+		return fmt.Errorf("failed to republish quiz question: %w", err)
+	}
+
+	if currentState == questionState {
+		machine.publishQuestion(question)
+	} else {
+		machine.publishAnswer(question)
+	}
+
+	return nil
+}
+
+// Publishes the given question to the question topic, with the deadline of the machine's timer,
+// and updates the session state.
+func (machine *QuizMachine) publishQuestion(question Question) {
+	machine.publish(QuestionTopic, QuestionMessage{
+		Message:    newMessage(MsgQuestion),
+		QuestionID: question.ID,
+		Question:   question.Question,
+		Index:      len(machine.questions),
+		Total:      machine.questionCount,
+		Language:   question.language(),
+		Mode:       machine.mode,
+		Options:    question.Options,
+		Media:      machine.mediaMessage(question),
+		Deadline:   machine.timer.deadline.UnixMilli(),
+	}, true)
+	machine.publishState(questionState)
+}
+
+// Publishes the outcome of the given question to the answer topic, with the deadline of the
+// machine's timer, and updates the session state. Expects the question to be graded.
+func (machine *QuizMachine) publishAnswer(question Question) {
+	machine.publish(AnswerTopic, AnswerMessage{
+		Message:    newMessage(MsgAnswer),
+		QuestionID: question.ID,
+		Question:   question.Question,
+		Answer:     machine.reveal.Answer,
+		Index:      len(machine.questions),
+		Total:      machine.questionCount,
+		Language:   question.language(),
+		Media:      machine.mediaMessage(question),
+		Tally:      machine.reveal.Tally,
+		Deadline:   machine.timer.deadline.UnixMilli(),
+	}, true)
+	machine.publishState(answerState)
+}
+
+// Publishes the quiz session's current state to the state topic, as retained message, so that
+// clients joining at any time are shown the same as everyone else.
+func (machine *QuizMachine) publishState(currentState stm.StateID) {
+	state := StateMessage{Message: newMessage(MsgState), State: SessionIdle}
+
+	question, err := machine.currentQuestion()
+	if currentState != idleState && err == nil {
+		state.State = SessionQuestion
+		if currentState == answerState {
+			state.State = SessionAnswer
+			state.Answer = machine.reveal.Answer
+			state.Tally = machine.reveal.Tally
+		}
+
+		state.QuestionID = question.ID
+		state.Question = question.Question
+		state.Index = len(machine.questions)
+		state.Total = machine.questionCount
+		state.Language = question.language()
+		state.Mode = machine.mode
+		state.Options = question.Options
+		state.Media = machine.mediaMessage(question)
+		state.Scoring = machine.scoring
+		state.TeamMode = machine.teamMode
+		state.Host = machine.host
+		state.Standings = machine.standings.ranked()
+
+		if machine.timer.paused {
+			state.Paused = true
+			state.Remaining = machine.timer.remaining.Milliseconds()
+		} else {
+			state.Deadline = machine.timer.deadline.UnixMilli()
+		}
+	}
+
+	machine.messenger.PublishJSON(StateTopic, state, true)
+}
+
+// Starts a new quiz session with the options from the given start command, resetting the standings
+// and clearing the previous session's leaderboard and results. Makes the command's sender the host
+// of the session. Uses the machine's default scoring strategy, team mode and game mode if the
+// command has none, or an unknown one, and the room's language if the command has none. Asks as
+// many questions as the question bank has for the game mode, up to maxQuestionCount. Returns
+// false, and replies to the sender with an error, if there are no questions to ask.
+func (machine *QuizMachine) startQuiz(command command) bool {
+	machine.language = machine.defaultLanguage
+	if command.startOptions.Language != "" {
+		machine.language = normalizeLanguage(command.startOptions.Language)
+	}
+
+	machine.mode = machine.defaultMode
+	if command.startOptions.Mode != "" {
+		if _, ok := gameModeByName(command.startOptions.Mode); ok {
+			machine.mode = command.startOptions.Mode
+		} else {
+			log.Printf("Unknown quiz game mode '%v', using default\n", command.startOptions.Mode)
+		}
+	}
+	machine.gameMode, _ = gameModeByName(machine.mode)
+
+	machine.questionCount = len(machine.askableQuestions())
+	if machine.questionCount == 0 {
+		log.Printf("Ignoring quiz start: no enabled questions for game mode '%v'\n", machine.mode)
+		machine.messenger.ReplyError(command.ClientID, command.Kind, "no questions available")
+		return false
+	} else if machine.questionCount > maxQuestionCount {
+		machine.questionCount = maxQuestionCount
+	}
+
+	startMessage := command.startOptions
+
+	machine.scoring = machine.defaultScoring
+	if startMessage.Scoring != "" {
+		if _, ok := scorerByName(startMessage.Scoring); ok {
+			machine.scoring = startMessage.Scoring
+		} else {
+			log.Printf("Unknown quiz scoring strategy '%v', using default\n", startMessage.Scoring)
+		}
+	}
+	machine.scorer, _ = scorerByName(machine.scoring)
+
+	machine.teamMode = machine.defaultTeamMode
+	if startMessage.TeamMode != TeamsOff {
+		if startMessage.TeamMode.valid() {
+			machine.teamMode = startMessage.TeamMode
+		} else {
+			log.Printf("Unknown quiz team mode '%v', using default\n", startMessage.TeamMode)
+		}
+	}
+
+	machine.host = command.ClientID
+	machine.participants = make([]string, 0)
+	machine.questions = make([]Question, 0)
+	machine.standings = newStandings(machine.teamMode)
+	machine.startedAt = time.Now()
+	machine.broker.Publish(LeaderboardTopic, []byte{}, true)
+	machine.broker.Publish(ResultsTopic, []byte{}, true)
+	return true
+}
+
+// Returns the enabled questions in the machine's question bank for the game mode of the current
+// quiz session, translated to the session's language according to the machine's translation
+// policy.
+func (machine *QuizMachine) askableQuestions() []Question {
+	questions := make([]Question, 0)
+	for _, question := range machine.questionBank.enabled() {
+		if question.mode() == machine.mode {
+			questions = append(questions, question)
+		}
+	}
+
+	return localizeQuestions(questions, machine.language, machine.translationPolicy)
+}
+
+// Returns when each question was last asked in the machine's room, by question ID, from the
+// machine's store. Returns an empty usage if the machine has no store, or reading it failed.
+func (machine *QuizMachine) questionUsage() map[int]time.Time {
+	if machine.store == nil {
+		return make(map[int]time.Time)
+	}
+
+	usage, err := machine.store.QuestionUsage(machine.room)
+	if err != nil {
+		log.Println("Failed to read quiz question usage:", err)
+		return make(map[int]time.Time)
+	}
+	return usage
+}
+
+// Records in the machine's store, if it has one, that the given question was asked in the
+// machine's room.
+func (machine *QuizMachine) recordQuestionAsked(question Question) {
+	if machine.store == nil {
+		return
+	}
+
+	err := machine.store.RecordQuestionAsked(machine.room, question.ID, time.Now())
+	if err != nil {
+		log.Println("Failed to record quiz question usage:", err)
+	}
+}
+
+// Publishes the quiz end message, cleans up the questions of the quiz session, and clears the
+// retained question and answer, so that clients joining between quizzes see no stale content.
+func (machine *QuizMachine) endQuiz() {
+	machine.publish(QuizStatusTopic, StatusMessage{newMessage(MsgEndQuiz)}, true)
+	machine.questions = make([]Question, 0)
+	machine.clearRetained()
+	machine.publishState(idleState)
+}
+
+// Clears the retained messages on the question and answer topics, so that clients subscribing
+// later do not receive them.
+func (machine *QuizMachine) clearRetained() {
+	// Publishing an empty retained payload removes the topic's retained message.
+	machine.broker.Publish(QuestionTopic, []byte{}, true)
+	machine.broker.Publish(AnswerTopic, []byte{}, true)
 }
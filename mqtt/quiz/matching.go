@@ -0,0 +1,212 @@
+package quiz
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rules for matching submitted answers against the accepted answers of a question.
+type MatchConfig struct {
+	// Ignores differences in letter case.
+	IgnoreCase bool `json:"ignoreCase"`
+
+	// Ignores diacritics, such that "Ljubljana" matches "Ljubljána", and "Tromsø" matches
+	// "Tromso".
+	IgnoreDiacritics bool `json:"ignoreDiacritics"`
+
+	// Ignores punctuation, such that "The die is cast." matches "The die is cast".
+	IgnorePunctuation bool `json:"ignorePunctuation"`
+
+	// Ignores leading and trailing whitespace, and treats repeated whitespace as a single space.
+	CollapseWhitespace bool `json:"collapseWhitespace"`
+
+	// Maximum absolute difference between a numeric answer and the accepted answer.
+	// Can be overridden per question with Question.Tolerance.
+	NumericTolerance float64 `json:"numericTolerance"`
+
+	// Maximum edit (Levenshtein) distance between a normalized answer and an accepted answer.
+	// Allows for typos, such that "Ulaanbaatar" matches "Ulaanbaataar". Not used for
+	// multiple-choice questions, where answers must match an option exactly after normalization.
+	MaxEditDistance int `json:"maxEditDistance"`
+
+	// Minimum length (in characters) of an accepted answer for edit distance to be allowed.
+	// Shorter answers must match exactly after normalization, so that e.g. "cat" does not match
+	// "car".
+	MinEditLength int `json:"minEditLength"`
+}
+
+// Answer matching rules used if none are configured.
+var DefaultMatchConfig = MatchConfig{
+	IgnoreCase:         true,
+	IgnoreDiacritics:   true,
+	IgnorePunctuation:  true,
+	CollapseWhitespace: true,
+	NumericTolerance:   0,
+	MaxEditDistance:    2,
+	MinEditLength:      5,
+}
+
+// Letters that do not decompose into a base letter and a diacritic in Unicode normalization, but
+// that should still be considered equal to their base letters when ignoring diacritics.
+var diacriticReplacer = strings.NewReplacer(
+	"ø", "o", "Ø", "O",
+	"æ", "ae", "Æ", "AE",
+	"œ", "oe", "Œ", "OE",
+	"ß", "ss",
+	"đ", "d", "Đ", "D",
+	"ł", "l", "Ł", "L",
+	"ı", "i",
+)
+
+// Returns whether the given submitted answer matches the question's answer, or any of its accepted
+// alternatives, according to the matching rules. Multiple-choice questions (with options) are never
+// matched by edit distance, as a wrong option may be only a few letters from the right one, such as
+// "Austria" and "Australia".
+func (config MatchConfig) Matches(question Question, answer string) bool {
+	if len(question.Options) > 0 {
+		config.MaxEditDistance = 0
+	}
+
+	tolerance := config.NumericTolerance
+	if question.Tolerance > 0 {
+		tolerance = question.Tolerance
+	}
+
+	normalizedAnswer := config.normalize(answer)
+
+	for _, accepted := range question.acceptedAnswers() {
+		normalizedAccepted := config.normalize(accepted)
+
+		// Numeric answers are compared by value, and never by edit distance.
+		if acceptedNumber, ok := parseNumber(normalizedAccepted); ok {
+			answerNumber, ok := parseNumber(normalizedAnswer)
+			if ok && math.Abs(answerNumber-acceptedNumber) <= tolerance {
+				return true
+			}
+			continue
+		}
+
+		if normalizedAnswer == normalizedAccepted {
+			return true
+		}
+
+		if config.MaxEditDistance > 0 &&
+			len([]rune(normalizedAccepted)) >= config.MinEditLength &&
+			editDistance(normalizedAnswer, normalizedAccepted) <= config.MaxEditDistance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Normalizes the given answer according to the matching rules.
+func (config MatchConfig) normalize(answer string) string {
+	if config.IgnoreDiacritics {
+		answer = removeDiacritics(answer)
+	}
+
+	if config.IgnoreCase {
+		answer = strings.ToLower(answer)
+	}
+
+	if config.IgnorePunctuation {
+		answer = strings.Map(func(char rune) rune {
+			// Keeps decimal separators and minus signs, which are significant in numbers.
+			if char == '.' || char == ',' || char == '-' {
+				return char
+			}
+			if unicode.IsPunct(char) {
+				return -1
+			}
+			return char
+		}, answer)
+		answer = strings.Trim(answer, ".,")
+	}
+
+	if config.CollapseWhitespace {
+		answer = strings.Join(strings.Fields(answer), " ")
+	}
+
+	return answer
+}
+
+// Returns the given string with diacritics removed from its letters.
+func removeDiacritics(value string) string {
+	value = diacriticReplacer.Replace(value)
+
+	// Decomposes letters into base letters and combining marks, then removes the marks.
+	removeMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(removeMarks, value)
+	if err != nil {
+		return value
+	}
+	return result
+}
+
+// Matches numbers with ',' as thousands separator, such as "1,000" or "12,345.6", but not "0,125".
+var thousandsSeparated = regexp.MustCompile(`^[+-]?[1-9][0-9]{0,2}(,[0-9]{3})+(\.[0-9]*)?$`)
+
+// Parses the given normalized answer as a number, accepting both '.' and ',' as decimal separator.
+// Commas followed by exactly three digits (after a leading group not starting with 0) are taken as
+// thousands separators, so that "1,000" is 1000 while "1,5" is 1.5. Returns ok=false if the
+// answer is not a number.
+func parseNumber(answer string) (number float64, ok bool) {
+	answer = strings.ReplaceAll(answer, " ", "")
+	if thousandsSeparated.MatchString(answer) {
+		answer = strings.ReplaceAll(answer, ",", "")
+	} else if strings.Count(answer, ",") == 1 && !strings.Contains(answer, ".") {
+		answer = strings.Replace(answer, ",", ".", 1)
+	}
+
+	number, err := strconv.ParseFloat(answer, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, false
+	}
+	return number, true
+}
+
+// Returns the Levenshtein distance between the given strings: the minimum number of single
+// character insertions, deletions or substitutions required to change one into the other.
+func editDistance(first string, second string) int {
+	firstRunes, secondRunes := []rune(first), []rune(second)
+
+	// Keeps only the previous and current row of the distance matrix.
+	previous := make([]int, len(secondRunes)+1)
+	current := make([]int, len(secondRunes)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(firstRunes); i++ {
+		current[0] = i
+		for j := 1; j <= len(secondRunes); j++ {
+			substitutionCost := 1
+			if firstRunes[i-1] == secondRunes[j-1] {
+				substitutionCost = 0
+			}
+
+			deletion := previous[j] + 1
+			insertion := current[j-1] + 1
+			substitution := previous[j-1] + substitutionCost
+
+			current[j] = deletion
+			if insertion < current[j] {
+				current[j] = insertion
+			}
+			if substitution < current[j] {
+				current[j] = substitution
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(secondRunes)]
+}
@@ -0,0 +1,111 @@
+package quiz
+
+import "testing"
+
+func TestMatchesOptions(t *testing.T) {
+	options := []string{"Austria", "Australia", "Austin", "Augusta"}
+
+	tests := []struct {
+		question Question
+		answer   string
+		want     bool
+	}{
+		{Question{Answer: "Australia", Options: options}, "Australia", true},
+		{Question{Answer: "Australia", Options: options}, "australia", true},
+		{Question{Answer: "Australia", Options: options}, "Austria", false},
+		{Question{Answer: "Australia", Options: options}, "Australa", false},
+		{Question{Answer: "Australia"}, "Australa", true},
+		{Question{Answer: "Australia"}, "Austria", true},
+	}
+
+	for _, test := range tests {
+		got := DefaultMatchConfig.Matches(test.question, test.answer)
+		if got != test.want {
+			t.Errorf(
+				"Matches(%q with options %q, %q) = %v, want %v",
+				test.question.Answer, test.question.Options, test.answer, got, test.want,
+			)
+		}
+	}
+}
+
+func TestTriviaGradeOptions(t *testing.T) {
+	question := Question{Answer: "Australia", Options: []string{"Austria", "Australia"}}
+	answers := map[string]string{"right": "australia", "wrong": "Austria"}
+
+	correct := TriviaMode{}.Grade(question, answers, DefaultMatchConfig)
+	if !correct["right"] || correct["wrong"] || len(correct) != 1 {
+		t.Errorf("Grade(%v) = %v, want only 'right' correct", answers, correct)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		question Question
+		answer   string
+		want     bool
+	}{
+		{Question{Answer: "Oslo"}, "oslo", true},
+		{Question{Answer: "Oslo"}, "  OSLO  ", true},
+		{Question{Answer: "Ljubljana"}, "Ljubljána", true},
+		{Question{Answer: "Tromsø"}, "Tromso", true},
+		{Question{Answer: "The die is cast."}, "the die   is cast", true},
+		{Question{Answer: "Ulaanbaatar"}, "Ulaanbaataar", true},
+		{Question{Answer: "Ulaanbaatar"}, "Ulan Bator", false},
+		{Question{Answer: "cat"}, "car", false},
+		{Question{Answer: "Mumbai", Alternatives: []string{"Bombay"}}, "bombay", true},
+		{Question{Answer: "Mumbai", Alternatives: []string{"Bombay"}}, "Delhi", false},
+		{Question{Answer: "100"}, "100.0", true},
+		{Question{Answer: "100"}, "101", false},
+		{Question{Answer: "100", Tolerance: 2}, "101", true},
+		{Question{Answer: "100", Tolerance: 2}, "103", false},
+		{Question{Answer: "1000"}, "1,000", true},
+		{Question{Answer: "1.5"}, "1,5", true},
+		{Question{Answer: "100"}, "hundred", false},
+		{Question{Answer: "Oslo"}, "", false},
+	}
+
+	for _, test := range tests {
+		got := DefaultMatchConfig.Matches(test.question, test.answer)
+		if got != test.want {
+			t.Errorf(
+				"Matches(%q with alternatives %q, %q) = %v, want %v",
+				test.question.Answer, test.question.Alternatives, test.answer, got, test.want,
+			)
+		}
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   float64
+		wantOk bool
+	}{
+		{"42", 42, true},
+		{"-3.5", -3.5, true},
+		{"+7", 7, true},
+		{"1,5", 1.5, true},
+		{"0,125", 0.125, true},
+		{"1,000", 1000, true},
+		{"1,000,000", 1000000, true},
+		{"12,345.6", 12345.6, true},
+		{"1 000", 1000, true},
+		{"1,2,3", 0, false},
+		{"1.000,5", 0, false},
+		{"abc", 0, false},
+		{"NaN", 0, false},
+		{"inf", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		got, ok := parseNumber(test.answer)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf(
+				"parseNumber(%q) = (%v, %v), want (%v, %v)",
+				test.answer, got, ok, test.want, test.wantOk,
+			)
+		}
+	}
+}
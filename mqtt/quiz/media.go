@@ -0,0 +1,187 @@
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// An image or audio clip shown with a question, stored as a file in the machine's media directory.
+type Media struct {
+	// Name of the media file in the media directory, such as "skyline-3.jpg". Should not reveal the
+	// answer, as players can see it.
+	File string `json:"file"`
+
+	// Type of the media. Inferred from the file extension if empty.
+	Type MediaType `json:"type,omitempty"`
+}
+
+// Types of media that questions can include.
+type MediaType string
+
+const (
+	MediaImage MediaType = "image"
+	MediaAudio MediaType = "audio"
+)
+
+// Media as published with a question, with the URL where clients can fetch it.
+type MediaMessage struct {
+	URL  string    `json:"url"`
+	Type MediaType `json:"type"`
+}
+
+// Supported media file extensions, and the media types they are inferred as.
+var mediaExtensions = map[string]MediaType{
+	".png":  MediaImage,
+	".jpg":  MediaImage,
+	".jpeg": MediaImage,
+	".gif":  MediaImage,
+	".webp": MediaImage,
+	".mp3":  MediaAudio,
+	".ogg":  MediaAudio,
+	".wav":  MediaAudio,
+	".m4a":  MediaAudio,
+}
+
+// Maximum size of media files uploaded through the HTTP API.
+const maxMediaSize = 10 << 20
+
+// Path prefix of the HTTP endpoints for media files.
+const mediaPath = "/quiz/media/"
+
+// Returns the media's type, inferred from its file extension if not set.
+func (media Media) mediaType() MediaType {
+	if media.Type != "" {
+		return media.Type
+	}
+	return mediaExtensions[strings.ToLower(filepath.Ext(media.File))]
+}
+
+// Returns error if the media's file name is not a plain file name with a supported extension, or
+// its type does not match the extension.
+func (media Media) validate() error {
+	err := validateMediaFile(media.File)
+	if err != nil {
+		return err
+	}
+
+	inferred := mediaExtensions[strings.ToLower(filepath.Ext(media.File))]
+	if media.Type != "" && media.Type != inferred {
+		return fmt.Errorf("media type '%v' does not match file '%v'", media.Type, media.File)
+	}
+
+	return nil
+}
+
+// Returns error if the given media file name is not a plain file name (without directories) with
+// a supported extension.
+func validateMediaFile(file string) error {
+	if file == "" || file != path.Base(file) || file != filepath.Base(file) ||
+		strings.HasPrefix(file, ".") {
+		return fmt.Errorf("invalid media file name '%v'", file)
+	}
+
+	if _, ok := mediaExtensions[strings.ToLower(filepath.Ext(file))]; !ok {
+		return fmt.Errorf("unsupported media file type '%v'", filepath.Ext(file))
+	}
+
+	return nil
+}
+
+// Returns the given question's media as published to clients, with its URL under the machine's
+// media URL. Returns nil if the question has no media.
+func (machine *QuizMachine) mediaMessage(question Question) *MediaMessage {
+	if question.Media == nil {
+		return nil
+	}
+
+	return &MediaMessage{
+		URL:  machine.mediaURL + question.Media.File,
+		Type: question.Media.mediaType(),
+	}
+}
+
+// HTTP handler for media files. Serves files from the machine's media directory on GET, and
+// uploads files to it on PUT (which requires the admin token).
+func (machine *QuizMachine) handleMedia(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet, http.MethodHead, http.MethodPut) {
+		return
+	}
+
+	if machine.mediaDir == "" {
+		http.Error(res, "quiz media is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	file := strings.TrimPrefix(req.URL.Path, mediaPath)
+	err := validateMediaFile(file)
+	if err != nil {
+		status := http.StatusNotFound
+		if req.Method == http.MethodPut {
+			status = http.StatusBadRequest
+		}
+		http.Error(res, err.Error(), status)
+		return
+	}
+
+	if req.Method != http.MethodPut {
+		http.ServeFile(res, req, filepath.Join(machine.mediaDir, file))
+		return
+	}
+
+	if !machine.checkAdmin(res, req) {
+		return
+	}
+
+	if req.ContentLength > maxMediaSize {
+		http.Error(res, errMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	err = machine.saveMedia(file, req.Body)
+	if errors.Is(err, errMediaTooLarge) {
+		http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		log.Println("Failed to save quiz media:", err)
+		http.Error(res, "failed to save media file", http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// Returned when uploading a media file larger than maxMediaSize.
+var errMediaTooLarge = errors.New("media file too large")
+
+// Writes the given content to the media file with the given name in the machine's media directory.
+// Writes to a temporary file first, so that the file is never served half-written. Returns
+// errMediaTooLarge, leaving the media directory unchanged, if the content is larger than
+// maxMediaSize.
+func (machine *QuizMachine) saveMedia(file string, content io.Reader) error {
+	tempFile, err := os.CreateTemp(machine.mediaDir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	// Reads one byte past the limit, to tell content of exactly the maximum size from larger.
+	written, err := io.Copy(tempFile, io.LimitReader(content, maxMediaSize+1))
+	if err == nil && written > maxMediaSize {
+		err = errMediaTooLarge
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), filepath.Join(machine.mediaDir, file))
+}
@@ -0,0 +1,54 @@
+package quiz
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveMediaSize(t *testing.T) {
+	tests := []struct {
+		size    int64
+		wantErr error
+	}{
+		{0, nil},
+		{maxMediaSize, nil},
+		{maxMediaSize + 1, errMediaTooLarge},
+		{maxMediaSize * 2, errMediaTooLarge},
+	}
+
+	for _, test := range tests {
+		machine := &QuizMachine{mediaDir: t.TempDir()}
+		content := io.LimitReader(zeroReader{}, test.size)
+
+		err := machine.saveMedia("image.png", content)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("saveMedia(%v bytes) = %v, want %v", test.size, err, test.wantErr)
+		}
+
+		_, statErr := os.Stat(filepath.Join(machine.mediaDir, "image.png"))
+		if saved := statErr == nil; saved != (test.wantErr == nil) {
+			t.Errorf("saveMedia(%v bytes) saved file: %v, want %v", test.size, saved, !saved)
+		}
+
+		entries, _ := os.ReadDir(machine.mediaDir)
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".upload-") {
+				t.Errorf("saveMedia(%v bytes) left temporary file %v", test.size, entry.Name())
+			}
+		}
+	}
+}
+
+// Reader of endless zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(buffer []byte) (int, error) {
+	for i := range buffer {
+		buffer[i] = 0
+	}
+	return len(buffer), nil
+}
@@ -1,9 +1,12 @@
 package quiz
 
 import (
+	"encoding/json"
 	"log"
+	"time"
 
-	"github.com/dcs-team4/coffeetalk/stm"
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	mqtt "github.com/mochi-co/mqtt/server"
 	"github.com/mochi-co/mqtt/server/events"
 )
 
@@ -15,10 +18,29 @@ const (
 	// Name of the MQTT topic where the server posts answers to quiz questions.
 	AnswerTopic string = "coffeetalk/quiz/answers"
 
-	// Name of the MQTT topic where clients post to start a quiz,
-	// and the server posts if the quiz is ended.
+	// Name of the MQTT topic where clients post to start and control a quiz,
+	// and the server posts if the quiz is paused or ended.
 	QuizStatusTopic string = "coffeetalk/quiz/status"
 
+	// Name of the MQTT topic where clients post their answers to quiz questions.
+	// Answers are only received by the server, and not passed on to other clients.
+	SubmissionTopic string = "coffeetalk/quiz/submissions"
+
+	// Name of the MQTT topic where the server posts the ranked standings of the current quiz
+	// session after each answer is revealed.
+	LeaderboardTopic string = "coffeetalk/quiz/leaderboard"
+
+	// Name of the MQTT topic where the server posts the final results when a quiz ends.
+	ResultsTopic string = "coffeetalk/quiz/results"
+
+	// Name of the MQTT topic where the server posts the current state of the quiz session, retained
+	// so that clients joining at any time see the same as everyone else.
+	StateTopic string = "coffeetalk/quiz/state"
+
+	// Prefix of the MQTT topics where the server replies to individual clients, followed by the
+	// client's ID. See ReplyTopic.
+	ReplyTopicPrefix string = "coffeetalk/quiz/replies/"
+
 	// The message posted on the MQTT quiz status topic to start a quiz.
 	QuizStartMessage string = "start-quiz"
 
@@ -26,19 +48,396 @@ const (
 	QuizEndMessage string = "end-quiz"
 )
 
-// Returns a handler for listening to MQTT messages.
-// When a start message is sent on the appropriate quiz topic,
-// triggers the Start event on the given quiz state machine.
-func (machine *QuizMachine) StartQuizHandler() events.OnMessage {
+// Version of the JSON message format on the quiz topics.
+// Should be incremented when making breaking changes to the message types below.
+const MessageVersion = 1
+
+// Types of JSON messages sent on the quiz topics.
+const (
+	MsgStartQuiz      string = QuizStartMessage
+	MsgEndQuiz        string = QuizEndMessage
+	MsgPauseQuiz      string = "pause-quiz"
+	MsgResumeQuiz     string = "resume-quiz"
+	MsgSkipQuestion   string = "skip-question"
+	MsgAbortQuiz      string = "abort-quiz"
+	MsgQuestion       string = "question"
+	MsgAnswer         string = "answer"
+	MsgSubmitAnswer   string = "submit-answer"
+	MsgLeaderboard    string = "leaderboard"
+	MsgResults        string = "results"
+	MsgState          string = "state"
+	MsgError          string = room.MsgError
+	MsgAnswerReceived string = "answer-received"
+)
+
+// States of a quiz session, as published on the state topic.
+type SessionState string
+
+const (
+	// No quiz is in progress.
+	SessionIdle SessionState = "idle"
+
+	// A question is being asked, and players can submit answers.
+	SessionQuestion SessionState = "question"
+
+	// The answer to the current question is being shown.
+	SessionAnswer SessionState = "answer"
+)
+
+// Formats for the payloads that the server publishes on the quiz topics.
+type PayloadFormat string
+
+const (
+	// Publishes versioned JSON messages, as defined by the message types below.
+	FormatJSON PayloadFormat = "json"
+
+	// Publishes plain-text payloads (question text, answer text, start/end messages),
+	// for compatibility with clients that do not yet parse JSON messages.
+	FormatText PayloadFormat = "text"
+)
+
+// Base struct to embed in all quiz message types.
+type Message = room.Message
+
+// Message posted by the server on the question topic when a new question is asked.
+type QuestionMessage struct {
+	Message           // Type: MsgQuestion
+	QuestionID int    `json:"questionId"`
+	Question   string `json:"question"`
+	Index      int    `json:"index"` // Number of the question in the quiz session, starting at 1.
+	Total      int    `json:"total"` // Number of questions in the quiz session.
+
+	// Language tag of the question text.
+	Language string `json:"language"`
+
+	// Name of the game mode of the quiz session, such as ModeTrivia.
+	Mode string `json:"mode"`
+
+	// Options to choose between, in game modes such as ModeWouldYouRather.
+	Options []string `json:"options,omitempty"`
+
+	// Image or audio clip to show with the question. Omitted if the question has none.
+	Media *MediaMessage `json:"media,omitempty"`
+
+	// Unix timestamp (in milliseconds) of when the answer to the question will be revealed.
+	Deadline int64 `json:"deadline"`
+}
+
+// Message posted by the server on the answer topic when the answer to a question is revealed.
+type AnswerMessage struct {
+	Message                  // Type: MsgAnswer
+	QuestionID int           `json:"questionId"`
+	Question   string        `json:"question"`
+	Answer     string        `json:"answer"`
+	Index      int           `json:"index"`
+	Total      int           `json:"total"`
+	Language   string        `json:"language"`
+	Media      *MediaMessage `json:"media,omitempty"`
+
+	// Number of competitors giving each answer, in game modes where the distribution of answers
+	// matters, such as ModeWouldYouRather.
+	Tally []TallyEntry `json:"tally,omitempty"`
+
+	// Unix timestamp (in milliseconds) of when the next question is asked, or the quiz ends.
+	Deadline int64 `json:"deadline"`
+}
+
+// Message posted by clients on the quiz status topic to start a quiz, with options for the quiz
+// session.
+type StartQuizMessage struct {
+	Message        // Type: MsgStartQuiz
+	Scoring string `json:"scoring,omitempty"` // Name of the scoring strategy for the session.
+
+	// How answers are combined per team, if the session is played in teams. Players compete
+	// individually if empty.
+	TeamMode TeamMode `json:"teamMode,omitempty"`
+
+	// Language tag of the session's questions, such as "nb". The room's language if empty.
+	Language string `json:"language,omitempty"`
+
+	// Name of the session's game mode, such as ModeEstimation. The room's default if empty.
+	Mode string `json:"mode,omitempty"`
+}
+
+// Message posted on the quiz status topic, by clients to start or control a quiz, and by the
+// server when the quiz is paused or ends.
+type StatusMessage struct {
+	Message // Type: MsgStartQuiz/MsgEndQuiz/MsgPauseQuiz/MsgResumeQuiz/MsgSkipQuestion/MsgAbortQuiz
+}
+
+// Message posted by clients on the submission topic to answer the current quiz question.
+type SubmitAnswerMessage struct {
+	Message           // Type: MsgSubmitAnswer
+	QuestionID int    `json:"questionId"`
+	Answer     string `json:"answer"`
+	Name       string `json:"name"` // Display name of the player, used in the leaderboard.
+
+	// Name of the team the player answers for in team mode. Office clients (named "Office …") are
+	// teamed up by office name if not set.
+	Team string `json:"team,omitempty"`
+}
+
+// Message posted by the server on the leaderboard topic after each answer is revealed.
+type LeaderboardMessage struct {
+	Message              // Type: MsgLeaderboard
+	Index     int        `json:"index"` // Number of questions answered so far.
+	Total     int        `json:"total"`
+	TeamMode  TeamMode   `json:"teamMode,omitempty"`
+	Standings []Standing `json:"standings"`
+}
+
+// Message posted by the server on the results topic when a quiz ends.
+type ResultsMessage struct {
+	Message                    // Type: MsgResults
+	Scoring   string           `json:"scoring"`
+	TeamMode  TeamMode         `json:"teamMode,omitempty"`
+	Mode      string           `json:"mode"`
+	Standings []Standing       `json:"standings"`
+	Questions []QuestionResult `json:"questions"`
+}
+
+// Message posted by the server on the state topic whenever the quiz session changes state, is
+// paused or is resumed. Describes everything clients need to show the current quiz session.
+type StateMessage struct {
+	Message              // Type: MsgState
+	State   SessionState `json:"state"`
+	Paused  bool         `json:"paused"`
+
+	// The current question. Omitted in the Idle state. The answer is only set in the Answer state.
+	QuestionID int    `json:"questionId,omitempty"`
+	Question   string `json:"question,omitempty"`
+	Answer     string `json:"answer,omitempty"`
+	Index      int    `json:"index"`
+	Total      int    `json:"total"`
+	Language   string `json:"language,omitempty"`
+
+	// Game mode of the quiz session, and the current question's options. Omitted in the Idle state.
+	Mode    string   `json:"mode,omitempty"`
+	Options []string `json:"options,omitempty"`
+
+	// Image or audio clip to show with the current question. Omitted if it has none.
+	Media *MediaMessage `json:"media,omitempty"`
+
+	// Number of competitors giving each answer, in the Answer state of game modes where the
+	// distribution of answers matters.
+	Tally []TallyEntry `json:"tally,omitempty"`
+
+	// Unix timestamp (in milliseconds) of when the current state ends. Omitted in the Idle state,
+	// and while the quiz is paused.
+	Deadline int64 `json:"deadline,omitempty"`
+
+	// Time (in milliseconds) left of the current state while the quiz is paused.
+	Remaining int64 `json:"remaining,omitempty"`
+
+	Scoring  string   `json:"scoring,omitempty"`
+	TeamMode TeamMode `json:"teamMode,omitempty"`
+
+	// Client ID of the quiz session's host, the only client (besides admins) allowed to control it.
+	// Empty if the session has no host, such as when started by a schedule, or when the host left
+	// with no other participant to hand over to. Until then, only admins can control the session,
+	// and the next client whose answer is accepted becomes host. Omitted in the Idle state.
+	Host string `json:"host,omitempty"`
+
+	// Standings of the quiz session after the questions answered so far. Omitted in the Idle
+	// state; the results of the previous session are on the results topic.
+	Standings []Standing `json:"standings,omitempty"`
+}
+
+// Message posted by the server on a client's reply topic when a message from the client, such as
+// MsgPauseQuiz, is rejected.
+type ErrorMessage = room.ErrorMessage
+
+// Message posted by the server on a client's reply topic when recording the client's answer to a
+// question.
+type AnswerReceivedMessage struct {
+	Message           // Type: MsgAnswerReceived
+	QuestionID int    `json:"questionId"`
+	Answer     string `json:"answer"`
+
+	// Whether the answer replaced the client's previous answer to the question.
+	Changed bool `json:"changed"`
+
+	// Whether the answer is final, as players cannot change their answers in the quiz.
+	Final bool `json:"final"`
+}
+
+// A player's, or a team's, position in the standings of a quiz session.
+type Standing struct {
+	Rank     int    `json:"rank"`               // Players with equal scores share the same rank.
+	ClientID string `json:"clientId,omitempty"` // Empty for teams.
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	Correct  int    `json:"correct"` // Number of correctly answered questions.
+
+	// Names of the team's players, in the order they joined. Empty for individual players.
+	Members []string `json:"members,omitempty"`
+}
+
+// Recap of a question asked in a quiz session, with the answers given by players.
+type QuestionResult struct {
+	QuestionID int          `json:"questionId"`
+	Question   string       `json:"question"`
+	Answer     string       `json:"answer"`
+	Tally      []TallyEntry `json:"tally,omitempty"`
+	Responses  []Response   `json:"responses"`
+}
+
+// A player's, or a team's, answer to a quiz question.
+type Response struct {
+	ClientID string `json:"clientId,omitempty"` // Empty for teams.
+	Name     string `json:"name"`
+	Answer   string `json:"answer"`
+	Correct  bool   `json:"correct"`
+	Points   int    `json:"points"`
+}
+
+// A message that can be published in the plain-text payload format.
+type legacyMessage interface {
+	// Returns the plain-text payload that clients expected before the introduction of JSON
+	// messages.
+	legacyPayload() string
+}
+
+// Returns the question text.
+func (message QuestionMessage) legacyPayload() string {
+	return message.Question
+}
+
+// Returns the answer text.
+func (message AnswerMessage) legacyPayload() string {
+	return message.Answer
+}
+
+// Returns the status message type, such as "end-quiz".
+func (message StatusMessage) legacyPayload() string {
+	return message.Type
+}
+
+// Returns the topic where the server replies to the client with the given ID.
+func ReplyTopic(clientID string) string {
+	return room.ReplyTopic(ReplyTopicPrefix, clientID)
+}
+
+// Returns a base message of the given type, with the current message version.
+func newMessage(messageType string) Message {
+	return Message{Version: MessageVersion, Type: messageType}
+}
+
+// Parses the given payload from the quiz status topic. Accepts both JSON status messages and the
+// plain-text start/end messages. Returns ok=false if the payload is not a recognized message.
+func parseStatusMessage(payload []byte) (message StatusMessage, ok bool) {
+	switch string(payload) {
+	case QuizStartMessage, QuizEndMessage:
+		return StatusMessage{newMessage(string(payload))}, true
+	}
+
+	err := json.Unmarshal(payload, &message)
+	if err != nil {
+		return StatusMessage{}, false
+	}
+
+	if message.Version != MessageVersion {
+		log.Printf("Unsupported quiz message version: %v\n", message.Version)
+		return StatusMessage{}, false
+	}
+
+	return message, true
+}
+
+// Parses the given payload from the submission topic as an answer submission.
+// Returns ok=false if the payload is not a valid submission.
+func parseSubmission(payload []byte) (message SubmitAnswerMessage, ok bool) {
+	err := json.Unmarshal(payload, &message)
+	if err != nil {
+		return SubmitAnswerMessage{}, false
+	}
+
+	if message.Version != MessageVersion || message.Type != MsgSubmitAnswer {
+		return SubmitAnswerMessage{}, false
+	}
+
+	return message, true
+}
+
+// Publishes the given message to the given topic on the machine's broker. Serializes the message
+// as JSON, or as plain text if the machine is configured with the plain-text payload format.
+func (machine *QuizMachine) publish(topic string, message legacyMessage, retain bool) {
+	if machine.payloadFormat == FormatText {
+		machine.broker.Publish(topic, []byte(message.legacyPayload()), retain)
+	} else {
+		machine.messenger.PublishJSON(topic, message, retain)
+	}
+}
+
+// Returns a handler for processing MQTT messages before they are passed on to subscribers.
+// When a start message is sent on the appropriate quiz topic, triggers the Start event on the given
+// quiz state machine. When a control command (pause, resume, skip, abort) is sent, passes it on to
+// the machine. Answer submissions are passed on to the machine, and rejected so that other clients
+// do not see them. Messages are dropped if the machine is not ready to receive them, so that the
+// broker is never blocked.
+func (machine *QuizMachine) MessageHandler() events.OnProcessMessage {
 	return func(client events.Client, packet events.Packet) (events.Packet, error) {
 		log.Printf(
 			"Message received (topic: %v, message: %v)\n", packet.TopicName, string(packet.Payload),
 		)
 
-		if packet.TopicName == QuizStatusTopic && string(packet.Payload) == QuizStartMessage {
-			machine.start <- stm.Trigger{}
+		switch packet.TopicName {
+		case QuizStatusTopic:
+			machine.handleStatusMessage(client, packet.Payload)
+		case SubmissionTopic:
+			machine.handleSubmission(client, packet.Payload)
+			return packet, mqtt.ErrRejectPacket
 		}
 
 		return packet, nil
 	}
 }
+
+// Handles the given payload from the quiz status topic, passing start messages and control
+// commands from the given client on to the machine.
+func (machine *QuizMachine) handleStatusMessage(client events.Client, payload []byte) {
+	message, ok := parseStatusMessage(payload)
+	if !ok {
+		return
+	}
+
+	command := command{Command: machine.messenger.Command(client.ID, message.Type)}
+
+	switch message.Type {
+	case MsgStartQuiz:
+		// Start options are only available in JSON start messages, not plain-text ones.
+		if json.Unmarshal(payload, &command.startOptions) != nil {
+			command.startOptions = StartQuizMessage{Message: message.Message}
+		}
+
+		select {
+		case machine.start <- command:
+		default:
+			log.Println("Quiz start dropped: quiz machine busy")
+		}
+	case MsgPauseQuiz, MsgResumeQuiz, MsgSkipQuestion, MsgAbortQuiz:
+		select {
+		case machine.commands <- command:
+		default:
+			log.Printf("Quiz command '%v' dropped: quiz machine busy\n", message.Type)
+		}
+	}
+}
+
+// Handles the given payload from the submission topic, passing valid answers from the given client
+// on to the machine.
+func (machine *QuizMachine) handleSubmission(client events.Client, payload []byte) {
+	message, ok := parseSubmission(payload)
+	if !ok {
+		log.Printf("Invalid answer submission from client ID %v\n", client.ID)
+		return
+	}
+
+	select {
+	case machine.submissions <- submission{
+		clientID: client.ID, message: message, receivedAt: time.Now(),
+	}:
+	default:
+		log.Printf("Answer submission from client ID %v dropped: quiz machine busy\n", client.ID)
+	}
+}
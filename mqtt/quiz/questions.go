@@ -1,22 +1,28 @@
 package quiz
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 )
 
-// Embedded file with a list of questions.
+// Embedded file with a list of questions, used to seed question banks.
 //go:embed questions.json
 var questionsJson []byte
 
-// Global list of questions. Should be populated with questionsJson if empty.
-var questions = make([]Question, 0)
+// Returns a copy of the embedded questions file (questions.json) that the server seeds question
+// banks with, such as for linting it.
+func EmbeddedQuestions() []byte {
+	return append([]byte(nil), questionsJson...)
+}
 
-// The number of questions that should be asked in a quiz session before ending it.
+// The number of questions that should be asked in a quiz session before ending it. Fewer are
+// asked if the question bank has fewer enabled questions.
 const maxQuestionCount = 5
 
 // Time to wait before moving between question, answer and next question.
@@ -25,60 +31,249 @@ const (
 	answerDuration   = 10 * time.Second
 )
 
+// Minimum time a question is shown for when it ends early, because every active client has
+// answered it.
+const minQuestionDuration = 5 * time.Second
+
 // Questions and corresponding answers that make up the quiz.
 // Includes an ID to check for question uniqueness, and json tags for reading from file.
 type Question struct {
 	ID       int    `json:"id"`
 	Question string `json:"question"`
-	Answer   string `json:"answer"`
+	Answer   string `json:"answer,omitempty"` // Not required by all game modes.
+
+	// Name of the game mode the question is played in, such as ModeEstimation. ModeTrivia if empty.
+	Mode string `json:"mode,omitempty"`
+
+	// Options to choose between, for game modes such as ModeWouldYouRather.
+	Options []string `json:"options,omitempty"`
+
+	// Other answers that should also be accepted as correct, such as alternative spellings.
+	Alternatives []string `json:"alternatives,omitempty"`
+
+	// Maximum difference from the answer to accept for numeric answers.
+	// Overrides MatchConfig.NumericTolerance if greater than 0.
+	Tolerance float64 `json:"tolerance,omitempty"`
+
+	// Image or audio clip to show with the question. Optional.
+	Media *Media `json:"media,omitempty"`
+
+	// Topic of the question, such as "Geography". Optional.
+	Category string `json:"category,omitempty"`
+
+	// How hard the question is. Optional.
+	Difficulty Difficulty `json:"difficulty,omitempty"`
+
+	// Disabled questions are kept in the question bank, but not asked in quizzes.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Language tag (such as "en" or "nb") of the question text and answers. DefaultLanguage if
+	// empty.
+	Language string `json:"language,omitempty"`
+
+	// Translations of the question text and answers, keyed by language tag.
+	Translations map[string]Translation `json:"translations,omitempty"`
 }
 
-// Selects a question pseudorandomly from the global questions list, excluding any question in the
-// given alreadyAsked list. Returns error if it failed to read questions, or all are already asked.
-func newQuestion(alreadyAsked []Question) (Question, error) {
-	// If questions are uninitialized, reads from file first.
-	if len(questions) == 0 {
-		err := readQuestions()
-		if err != nil {
-			return Question{}, err
-		}
+// How hard a question is.
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyMedium Difficulty = "medium"
+	DifficultyHard   Difficulty = "hard"
+)
+
+// Returns whether the difficulty is one of the defined difficulties, or empty.
+func (difficulty Difficulty) valid() bool {
+	switch difficulty {
+	case "", DifficultyEasy, DifficultyMedium, DifficultyHard:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the name of the game mode the question is played in.
+func (question Question) mode() string {
+	if question.Mode == "" {
+		return ModeTrivia
 	}
+	return question.Mode
+}
+
+// Returns the question text in lower case with whitespace collapsed, for finding questions with
+// the same text.
+func (question Question) textKey() string {
+	return strings.ToLower(strings.Join(strings.Fields(question.Question), " "))
+}
 
+// Returns the question's answer followed by its accepted alternatives.
+func (question Question) acceptedAnswers() []string {
+	return append([]string{question.Answer}, question.Alternatives...)
+}
+
+// Selects a question from the given askable questions, excluding any question in the given
+// alreadyAsked list. Prefers the least recently asked questions according to the given
+// usage (mapping question IDs to when they were last asked; never asked questions are preferred
+// first), and picks pseudorandomly among equally recent ones. Returns error if all are already
+// asked.
+func newQuestion(
+	askable []Question, alreadyAsked []Question, usage map[int]time.Time,
+) (Question, error) {
 	// Filters out already asked questions.
 	notAsked := make([]Question, 0)
 outerLoop:
-	for _, askable := range questions {
+	for _, question := range askable {
 		for _, asked := range alreadyAsked {
-			if askable.ID == asked.ID {
+			if question.ID == asked.ID {
 				continue outerLoop
 			}
 		}
 
-		notAsked = append(notAsked, askable)
+		notAsked = append(notAsked, question)
 	}
 
 	if len(notAsked) == 0 {
 		return Question{}, errors.New("tried to get new question when all were asked")
 	}
 
+	// Keeps only the least recently asked questions. Never asked questions have zero time.
+	var oldestAskedAt time.Time
+	leastRecent := make([]Question, 0)
+	for i, question := range notAsked {
+		askedAt := usage[question.ID]
+		if i == 0 || askedAt.Before(oldestAskedAt) {
+			oldestAskedAt = askedAt
+			leastRecent = leastRecent[:0]
+		}
+		if askedAt.Equal(oldestAskedAt) {
+			leastRecent = append(leastRecent, question)
+		}
+	}
+
 	// Uses pseudo-random seed to select question.
 	rand.Seed(time.Now().UnixNano())
-	randomQuestion := notAsked[rand.Intn(len(notAsked))]
+	randomQuestion := leastRecent[rand.Intn(len(leastRecent))]
 	return randomQuestion, nil
 }
 
-// Reads questions from the embedded questions file, and sets the global questions variable to it.
-// Returns error if file reading failed, or questions are misconfigured.
-func readQuestions() error {
+// Parses the given JSON list of questions, and validates them.
+// Returns error if parsing failed, or questions are misconfigured.
+func readQuestions(questionsJson []byte) ([]Question, error) {
+	questions, err := parseQuestions(questionsJson)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int]bool)
+	for _, question := range questions {
+		err := question.validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid question %v: %w", question.ID, err)
+		}
+
+		if ids[question.ID] {
+			return nil, fmt.Errorf("duplicate question ID %v", question.ID)
+		}
+		ids[question.ID] = true
+	}
+
+	return questions, nil
+}
+
+// Parses the given JSON list of questions, without validating them. Returns error if the JSON is
+// malformed, with the line and column of the problem if known.
+func parseQuestions(questionsJson []byte) ([]Question, error) {
+	var questions []Question
 	err := json.Unmarshal(questionsJson, &questions)
+	if err == nil {
+		return questions, nil
+	}
+
+	var offset int64 = -1
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &syntaxError) {
+		offset = syntaxError.Offset
+	} else if errors.As(err, &typeError) {
+		offset = typeError.Offset
+	}
+
+	if offset < 0 {
+		return nil, fmt.Errorf("failed to parse questions: %w", err)
+	}
+
+	// Offsets point just past the problem, so the line and column are of its last byte.
+	before := questionsJson[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n') - 1
+	return nil, fmt.Errorf("failed to parse questions (line %v, column %v): %w", line, column, err)
+}
+
+// Returns error if the question is missing required fields, or has invalid values.
+func (question Question) validate() error {
+	if question.ID <= 0 {
+		return errors.New("id must be a positive integer")
+	}
+
+	if strings.TrimSpace(question.Question) == "" {
+		return errors.New("question text is required")
+	}
+
+	mode, ok := gameModeByName(question.mode())
+	if !ok {
+		return fmt.Errorf("unknown game mode '%v'", question.Mode)
+	}
+
+	err := mode.Validate(question)
 	if err != nil {
-		return fmt.Errorf("failed to read questions.json: %w", err)
+		return err
 	}
 
-	if len(questions) < maxQuestionCount {
-		return fmt.Errorf(
-			"too few configured questions: have %v, want %v", len(questions), maxQuestionCount,
-		)
+	options := make(map[string]bool)
+	for _, option := range question.Options {
+		if strings.TrimSpace(option) == "" {
+			return errors.New("options cannot be empty")
+		}
+
+		key := strings.ToLower(strings.TrimSpace(option))
+		if options[key] {
+			return fmt.Errorf("duplicate option '%v'", option)
+		}
+		options[key] = true
+	}
+
+	for _, alternative := range question.Alternatives {
+		if strings.TrimSpace(alternative) == "" {
+			return errors.New("alternative answers cannot be empty")
+		}
+	}
+
+	if question.Tolerance < 0 {
+		return errors.New("tolerance cannot be negative")
+	}
+
+	if !question.Difficulty.valid() {
+		return fmt.Errorf("unknown difficulty '%v'", question.Difficulty)
+	}
+
+	if question.Media != nil {
+		err = question.Media.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	for language, translation := range question.Translations {
+		if normalizeLanguage(language) == "" {
+			return errors.New("translation language cannot be empty")
+		}
+
+		err := translation.validate(question)
+		if err != nil {
+			return fmt.Errorf("invalid translation '%v': %w", language, err)
+		}
 	}
 
 	return nil
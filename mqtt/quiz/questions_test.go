@@ -0,0 +1,39 @@
+package quiz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseQuestionsPosition(t *testing.T) {
+	tests := []struct {
+		name         string
+		json         string
+		wantPosition string
+	}{
+		{
+			"missing comma",
+			"[\n  {\"id\": 1,\n   \"question\": \"Q\" \"answer\": \"A\"}\n]",
+			"(line 3, column 20)",
+		},
+		{"wrong type", "[\n  {\"id\": \"one\"}\n]", "(line 2, column 14)"},
+		{"trailing comma", "[\n  {\"id\": 1},\n]", "(line 3, column 1)"},
+		{"unterminated", "[{\"id\": 1}", "(line 1, column 10)"},
+		{"not a list", "{}", "(line 1, column 1)"},
+	}
+
+	for _, test := range tests {
+		_, err := parseQuestions([]byte(test.json))
+		if err == nil || !strings.Contains(err.Error(), test.wantPosition) {
+			t.Errorf(
+				"%v: parseQuestions() error = %v, want position %v",
+				test.name, err, test.wantPosition,
+			)
+		}
+	}
+
+	questions, err := parseQuestions([]byte(`[{"id": 1, "question": "Q", "answer": "A"}]`))
+	if err != nil || len(questions) != 1 || questions[0].Answer != "A" {
+		t.Errorf("parseQuestions() of valid JSON = (%v, %v), want 1 question", questions, err)
+	}
+}
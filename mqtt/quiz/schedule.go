@@ -0,0 +1,155 @@
+package quiz
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/dcs-team4/coffeetalk/mqtt/internal/room"
+	"github.com/robfig/cron/v3"
+)
+
+// A recurring schedule for starting quizzes automatically, such as at an office's coffee break.
+type Schedule struct {
+	// Name of the schedule, for logs and listings.
+	Name string `json:"name"`
+
+	// Name of the quiz room the schedule applies to. Applies to any room if empty.
+	Room string `json:"room,omitempty"`
+
+	// Standard 5-field cron expression for when to start quizzes, such as "0 10 * * MON-FRI" for
+	// 10:00 on weekdays.
+	Cron string `json:"cron"`
+
+	// IANA time zone of the cron expression, such as "Europe/Oslo". Defaults to the server's local
+	// time zone if empty.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Options for the scheduled quiz sessions. The machine's defaults are used if empty.
+	Scoring  string   `json:"scoring,omitempty"`
+	TeamMode TeamMode `json:"teamMode,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Mode     string   `json:"mode,omitempty"`
+}
+
+// A schedule as listed by the quiz HTTP API, with the time of its next quiz.
+type ScheduleStatus struct {
+	Schedule
+	Next time.Time `json:"next"`
+}
+
+// A schedule registered on the quiz machine's scheduler.
+type scheduledQuiz struct {
+	Schedule
+	cronSchedule cron.Schedule
+}
+
+// Parses the schedule's cron expression in its time zone.
+// Returns error if the expression or time zone is invalid.
+func (schedule Schedule) parse() (cron.Schedule, error) {
+	spec := schedule.Cron
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, fmt.Errorf(
+				"invalid time zone '%v' in quiz schedule '%v': %w",
+				schedule.TimeZone,
+				schedule.Name,
+				err,
+			)
+		}
+		spec = fmt.Sprintf("CRON_TZ=%v %v", schedule.TimeZone, spec)
+	}
+
+	cronSchedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"invalid cron expression '%v' in quiz schedule '%v': %w",
+			schedule.Cron,
+			schedule.Name,
+			err,
+		)
+	}
+
+	return cronSchedule, nil
+}
+
+// Registers the given schedules for the machine's room on the machine's scheduler. Skips schedules
+// for other rooms, and logs and skips invalid schedules.
+func (machine *QuizMachine) addSchedules(schedules []Schedule) {
+	for _, schedule := range schedules {
+		if schedule.Room != "" && schedule.Room != machine.room {
+			continue
+		}
+
+		cronSchedule, err := schedule.parse()
+		if err != nil {
+			log.Println("Skipping quiz schedule:", err)
+			continue
+		}
+
+		schedule := schedule
+		machine.scheduler.Schedule(cronSchedule, cron.FuncJob(func() {
+			machine.startScheduledQuiz(schedule)
+		}))
+		machine.schedules = append(
+			machine.schedules, scheduledQuiz{Schedule: schedule, cronSchedule: cronSchedule},
+		)
+	}
+}
+
+// Starts a quiz session with the options of the given schedule, unless no clients are connected to
+// the broker, a quiz is already in progress, or the machine rejects the start (such as when there
+// are no questions for the schedule's game mode). Scheduled sessions start without a host, so the
+// first client to answer becomes host.
+func (machine *QuizMachine) startScheduledQuiz(schedule Schedule) {
+	if atomic.LoadInt64(&machine.broker.System.ClientsConnected) == 0 {
+		log.Printf("Skipping scheduled quiz '%v': no clients connected\n", schedule.Name)
+		return
+	}
+
+	started := make(chan bool, 1)
+	command := command{
+		Command: room.Command{Kind: MsgStartQuiz},
+		startOptions: StartQuizMessage{
+			Message:  newMessage(MsgStartQuiz),
+			Scoring:  schedule.Scoring,
+			TeamMode: schedule.TeamMode,
+			Language: schedule.Language,
+			Mode:     schedule.Mode,
+		},
+		started: started,
+	}
+
+	select {
+	case machine.start <- command:
+	default:
+		log.Printf("Skipping scheduled quiz '%v': quiz machine busy\n", schedule.Name)
+		return
+	}
+
+	if <-started {
+		log.Printf("Started scheduled quiz '%v'\n", schedule.Name)
+	} else {
+		log.Printf("Skipping scheduled quiz '%v': quiz machine rejected start\n", schedule.Name)
+	}
+}
+
+// HTTP handler for listing the machine's quiz schedules, with the time of their next quiz.
+func (machine *QuizMachine) handleSchedules(res http.ResponseWriter, req *http.Request) {
+	if !checkMethod(res, req, http.MethodGet) {
+		return
+	}
+
+	now := time.Now()
+	statuses := make([]ScheduleStatus, 0, len(machine.schedules))
+	for _, schedule := range machine.schedules {
+		statuses = append(statuses, ScheduleStatus{
+			Schedule: schedule.Schedule,
+			Next:     schedule.cronSchedule.Next(now),
+		})
+	}
+
+	writeJSON(res, statuses)
+}
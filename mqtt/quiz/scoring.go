@@ -0,0 +1,120 @@
+package quiz
+
+import (
+	"math"
+	"time"
+)
+
+// A strategy for awarding points to answers in a quiz session.
+type Scorer interface {
+	// Returns the points awarded for the given graded answer.
+	Score(answer GradedAnswer) int
+}
+
+// An answer to a quiz question after grading, with the information needed to score it.
+type GradedAnswer struct {
+	Correct bool
+
+	// Time from the question was asked until the answer was submitted, excluding paused time.
+	ResponseTime time.Duration
+
+	// The time players had to answer the question.
+	TimeLimit time.Duration
+
+	// Number of consecutive questions the player answered correctly before this one.
+	Streak int
+}
+
+// Names of the scoring strategies that can be picked when starting a quiz.
+const (
+	// 1 point per correct answer.
+	ScoringClassic string = "classic"
+
+	// Up to 1000 points per correct answer, decaying to 500 points over the question's time limit.
+	ScoringTimed string = "timed"
+
+	// Classic scoring, with a multiplier growing with each consecutive correct answer.
+	ScoringStreak string = "streak"
+
+	// Timed scoring, with a multiplier growing with each consecutive correct answer.
+	ScoringTimedStreak string = "timed-streak"
+)
+
+// Scoring strategies that can be picked by name when starting a quiz.
+var scorers = map[string]Scorer{
+	ScoringClassic: CorrectnessScorer{},
+	ScoringTimed:   TimeDecayScorer{MaxPoints: 1000, MinPoints: 500},
+	ScoringStreak: StreakScorer{
+		Base: CorrectnessScorer{}, BonusPerStreak: 0.5, MaxMultiplier: 3,
+	},
+	ScoringTimedStreak: StreakScorer{
+		Base:           TimeDecayScorer{MaxPoints: 1000, MinPoints: 500},
+		BonusPerStreak: 0.25,
+		MaxMultiplier:  2,
+	},
+}
+
+// Returns the scoring strategy with the given name, or ok=false if there is none.
+func scorerByName(name string) (scorer Scorer, ok bool) {
+	scorer, ok = scorers[name]
+	return scorer, ok
+}
+
+// Scorer awarding 1 point per correct answer.
+type CorrectnessScorer struct{}
+
+// Returns 1 point for a correct answer, otherwise 0.
+func (CorrectnessScorer) Score(answer GradedAnswer) int {
+	if answer.Correct {
+		return 1
+	}
+	return 0
+}
+
+// Scorer awarding points to correct answers that decay linearly with response time, from
+// MaxPoints for an instant answer to MinPoints for an answer at the end of the time limit.
+type TimeDecayScorer struct {
+	MaxPoints int
+	MinPoints int
+}
+
+// Returns points for a correct answer based on its response time, otherwise 0.
+func (scorer TimeDecayScorer) Score(answer GradedAnswer) int {
+	if !answer.Correct {
+		return 0
+	}
+
+	if answer.TimeLimit <= 0 {
+		return scorer.MaxPoints
+	}
+
+	remainingShare := 1 - float64(answer.ResponseTime)/float64(answer.TimeLimit)
+	if remainingShare < 0 {
+		remainingShare = 0
+	} else if remainingShare > 1 {
+		remainingShare = 1
+	}
+
+	return scorer.MinPoints + int(float64(scorer.MaxPoints-scorer.MinPoints)*remainingShare)
+}
+
+// Scorer multiplying the points of a base scorer for players on a streak of correct answers.
+// The multiplier grows by BonusPerStreak for each previous consecutive correct answer, up to
+// MaxMultiplier.
+type StreakScorer struct {
+	Base           Scorer
+	BonusPerStreak float64
+	MaxMultiplier  float64
+}
+
+// Returns the base scorer's points for the answer, multiplied by the player's streak multiplier and
+// rounded to the nearest point, so that small bases (such as 1 point per correct answer) still
+// gain from fractional multipliers.
+func (scorer StreakScorer) Score(answer GradedAnswer) int {
+	multiplier := 1 + scorer.BonusPerStreak*float64(answer.Streak)
+	if scorer.MaxMultiplier > 0 && multiplier > scorer.MaxMultiplier {
+		multiplier = scorer.MaxMultiplier
+	}
+
+	return int(math.Round(float64(scorer.Base.Score(answer)) * multiplier))
+}
@@ -0,0 +1,49 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScorers(t *testing.T) {
+	limit := 30 * time.Second
+
+	tests := []struct {
+		scoring string
+		answer  GradedAnswer
+		want    int
+	}{
+		{ScoringClassic, GradedAnswer{Correct: true}, 1},
+		{ScoringClassic, GradedAnswer{Correct: false}, 0},
+		{ScoringTimed, GradedAnswer{Correct: true, TimeLimit: limit}, 1000},
+		{ScoringTimed, GradedAnswer{Correct: true, ResponseTime: limit / 2, TimeLimit: limit}, 750},
+		{ScoringTimed, GradedAnswer{Correct: true, ResponseTime: limit, TimeLimit: limit}, 500},
+		{ScoringTimed, GradedAnswer{Correct: true, ResponseTime: 2 * limit, TimeLimit: limit}, 500},
+		{ScoringTimed, GradedAnswer{Correct: true, ResponseTime: time.Second}, 1000},
+		{ScoringTimed, GradedAnswer{Correct: false, TimeLimit: limit}, 0},
+		{ScoringStreak, GradedAnswer{Correct: true}, 1},
+		{ScoringStreak, GradedAnswer{Correct: true, Streak: 1}, 2},
+		{ScoringStreak, GradedAnswer{Correct: true, Streak: 2}, 2},
+		{ScoringStreak, GradedAnswer{Correct: true, Streak: 3}, 3},
+		{ScoringStreak, GradedAnswer{Correct: true, Streak: 10}, 3},
+		{ScoringStreak, GradedAnswer{Correct: false, Streak: 10}, 0},
+		{ScoringTimedStreak, GradedAnswer{Correct: true, TimeLimit: limit, Streak: 1}, 1250},
+		{
+			ScoringTimedStreak,
+			GradedAnswer{Correct: true, ResponseTime: limit / 2, TimeLimit: limit, Streak: 1},
+			938,
+		},
+		{ScoringTimedStreak, GradedAnswer{Correct: true, TimeLimit: limit, Streak: 10}, 2000},
+	}
+
+	for _, test := range tests {
+		scorer, ok := scorerByName(test.scoring)
+		if !ok {
+			t.Fatalf("scorerByName(%q) found no scorer", test.scoring)
+		}
+
+		if got := scorer.Score(test.answer); got != test.want {
+			t.Errorf("%v Score(%+v) = %v, want %v", test.scoring, test.answer, got, test.want)
+		}
+	}
+}
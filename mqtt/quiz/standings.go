@@ -0,0 +1,312 @@
+package quiz
+
+import (
+	"sort"
+	"time"
+)
+
+// Scores and answers of the competitors in a quiz session, keyed by competitor key: the MQTT client
+// ID of a player, or the team key in team mode.
+type standings struct {
+	competitors map[string]*competitor
+
+	// Outcomes of the graded questions, keyed by question ID.
+	reveals map[int]Reveal
+
+	// How answers from members of the same team are combined. TeamsOff if not in team mode.
+	teamMode TeamMode
+}
+
+// A player, or a team of players in team mode, that has submitted answers in a quiz session.
+type competitor struct {
+	key  string
+	name string
+
+	// The clients that have answered on behalf of the competitor, in the order they joined.
+	// A single client for individual players.
+	members []member
+
+	score int
+
+	// Number of consecutive questions the competitor has answered correctly.
+	streak int
+
+	// Answers submitted by the competitor's members, keyed by question ID. Each member has at most
+	// one answer per question, kept in the order that members first answered.
+	submissions map[int][]*memberAnswer
+
+	// The competitor's final answers after grading, keyed by question ID.
+	answers map[int]*gradedAnswer
+}
+
+// A client answering on behalf of a competitor.
+type member struct {
+	clientID string
+	name     string
+}
+
+// An answer submitted by a client to a quiz question.
+type memberAnswer struct {
+	clientID string
+	answer   string
+
+	// Time from the question was asked until the answer was submitted.
+	responseTime time.Duration
+}
+
+// A competitor's final answer to a quiz question, after grading.
+type gradedAnswer struct {
+	answer  string
+	correct bool
+	points  int
+}
+
+// Returns new, empty standings for a quiz session with the given team mode.
+func newStandings(teamMode TeamMode) *standings {
+	return &standings{
+		competitors: make(map[string]*competitor),
+		reveals:     make(map[int]Reveal),
+		teamMode:    teamMode,
+	}
+}
+
+// Records the given answer to the given question from the client with the given ID and name,
+// creating its competitor if it has not answered before. In team mode, the client is added to the
+// team given by the team name (or by its own name, for office clients). Replaces any previous
+// answer from the same client to the same question.
+func (standings *standings) recordAnswer(
+	clientID string,
+	name string,
+	teamName string,
+	questionID int,
+	answer string,
+	responseTime time.Duration,
+	allowChange bool,
+) (changed bool, err error) {
+	if name == "" {
+		name = clientID
+	}
+
+	key, displayName := clientID, name
+	if standings.teamMode != TeamsOff {
+		key, displayName = teamOf(clientID, name, teamName)
+	}
+
+	submitter, ok := standings.competitors[key]
+	if !ok {
+		submitter = &competitor{
+			key:         key,
+			name:        displayName,
+			submissions: make(map[int][]*memberAnswer),
+			answers:     make(map[int]*gradedAnswer),
+		}
+		standings.competitors[key] = submitter
+	}
+
+	// Lets players update their display name between answers, while teams keep the name they were
+	// first given.
+	if standings.teamMode == TeamsOff {
+		submitter.name = displayName
+	}
+	submitter.addMember(clientID, name)
+
+	submissions := submitter.submissions[questionID]
+	for _, submission := range submissions {
+		if submission.clientID == clientID {
+			if !allowChange {
+				return false, errAnswerLocked
+			}
+
+			submission.answer = answer
+			submission.responseTime = responseTime
+			return true, nil
+		}
+	}
+	submitter.submissions[questionID] = append(submissions, &memberAnswer{
+		clientID: clientID, answer: answer, responseTime: responseTime,
+	})
+	return false, nil
+}
+
+// Returns whether the client with the given ID has answered the question with the given ID.
+func (standings *standings) hasAnswered(clientID string, questionID int) bool {
+	for _, competitor := range standings.competitors {
+		for _, submission := range competitor.submissions[questionID] {
+			if submission.clientID == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Adds the client with the given ID and name to the competitor's members, or updates its name if it
+// is already a member.
+func (competitor *competitor) addMember(clientID string, name string) {
+	for i, existing := range competitor.members {
+		if existing.clientID == clientID {
+			competitor.members[i].name = name
+			return
+		}
+	}
+
+	competitor.members = append(competitor.members, member{clientID: clientID, name: name})
+}
+
+// Grades every competitor's answer to the given question in the given game mode, using the given
+// matching rules, and adds the points awarded by the given scorer to each competitor's score (if
+// the game mode is scored). The given time limit is the time players had to answer the question.
+// In team mode, the answers of each team's members are first combined into one according to the
+// team mode. Returns the outcome of the question to reveal to players.
+func (standings *standings) grade(
+	question Question,
+	mode GameMode,
+	matching MatchConfig,
+	scorer Scorer,
+	timeLimit time.Duration,
+) Reveal {
+	submissions := make(map[string]*memberAnswer)
+	answers := make(map[string]string)
+	for key, competitor := range standings.competitors {
+		if _, graded := competitor.answers[question.ID]; graded {
+			continue
+		}
+
+		submission := standings.teamMode.combine(
+			competitor.submissions[question.ID], competitor.members, matching,
+		)
+		if submission == nil {
+			// Not answering breaks the competitor's streak.
+			competitor.streak = 0
+			continue
+		}
+
+		submissions[key] = submission
+		answers[key] = submission.answer
+	}
+
+	correct := mode.Grade(question, answers, matching)
+
+	for key, submission := range submissions {
+		competitor := standings.competitors[key]
+
+		answer := &gradedAnswer{answer: submission.answer, correct: correct[key]}
+		if !mode.Scored() {
+			competitor.answers[question.ID] = answer
+			continue
+		}
+
+		answer.points = scorer.Score(GradedAnswer{
+			Correct:      answer.correct,
+			ResponseTime: submission.responseTime,
+			TimeLimit:    timeLimit,
+			Streak:       competitor.streak,
+		})
+		competitor.answers[question.ID] = answer
+		competitor.score += answer.points
+
+		if answer.correct {
+			competitor.streak++
+		} else {
+			competitor.streak = 0
+		}
+	}
+
+	reveal := mode.Reveal(question, answers, matching)
+	standings.reveals[question.ID] = reveal
+	return reveal
+}
+
+// Returns the competitors ranked by score, with the highest score first. Competitors with equal
+// scores share the same rank, and are ordered by name.
+func (standings *standings) ranked() []Standing {
+	ranked := make([]Standing, 0, len(standings.competitors))
+	for _, competitor := range standings.competitors {
+		correctCount := 0
+		for _, answer := range competitor.answers {
+			if answer.correct {
+				correctCount++
+			}
+		}
+
+		standing := Standing{
+			Name:    competitor.name,
+			Score:   competitor.score,
+			Correct: correctCount,
+		}
+		if standings.teamMode == TeamsOff {
+			standing.ClientID = competitor.key
+		} else {
+			standing.Members = competitor.memberNames()
+		}
+
+		ranked = append(ranked, standing)
+	}
+
+	sort.Slice(ranked, func(i int, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+
+	for i := range ranked {
+		if i > 0 && ranked[i].Score == ranked[i-1].Score {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+
+	return ranked
+}
+
+// Returns the names of the competitor's members, in the order they joined.
+func (competitor *competitor) memberNames() []string {
+	names := make([]string, 0, len(competitor.members))
+	for _, member := range competitor.members {
+		names = append(names, member.name)
+	}
+	return names
+}
+
+// Returns a recap of the given questions, with their revealed outcome and every competitor's graded
+// answer to each of them.
+func (standings *standings) recap(questions []Question) []QuestionResult {
+	results := make([]QuestionResult, 0, len(questions))
+	for _, question := range questions {
+		responses := make([]Response, 0)
+		for _, competitor := range standings.competitors {
+			answer, ok := competitor.answers[question.ID]
+			if !ok {
+				continue
+			}
+
+			response := Response{
+				Name:    competitor.name,
+				Answer:  answer.answer,
+				Correct: answer.correct,
+				Points:  answer.points,
+			}
+			if standings.teamMode == TeamsOff {
+				response.ClientID = competitor.key
+			}
+
+			responses = append(responses, response)
+		}
+
+		sort.Slice(responses, func(i int, j int) bool {
+			return responses[i].Name < responses[j].Name
+		})
+
+		results = append(results, QuestionResult{
+			QuestionID: question.ID,
+			Question:   question.Question,
+			Answer:     standings.reveals[question.ID].Answer,
+			Tally:      standings.reveals[question.ID].Tally,
+			Responses:  responses,
+		})
+	}
+
+	return results
+}
@@ -0,0 +1,267 @@
+package quiz
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Persistent storage of quiz data, backed by an embedded bbolt database file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Names of the buckets in the store's database.
+var (
+	sessionsBucket = []byte("sessions")
+
+	// Holds a nested bucket per quiz room, mapping question IDs to when they were last asked.
+	questionUsageBucket = []byte("question-usage")
+)
+
+// A completed quiz session, as stored in the quiz history.
+type Session struct {
+	ID        uint64           `json:"id"`
+	Room      string           `json:"room"`
+	Scoring   string           `json:"scoring"`
+	TeamMode  TeamMode         `json:"teamMode,omitempty"`
+	Mode      string           `json:"mode,omitempty"`
+	Language  string           `json:"language,omitempty"`
+	StartedAt time.Time        `json:"startedAt"`
+	EndedAt   time.Time        `json:"endedAt"`
+	Standings []Standing       `json:"standings"`
+	Questions []QuestionResult `json:"questions"` // Questions asked, with the answers given.
+}
+
+// A player's statistics across quiz sessions. Players are identified by name, as MQTT client IDs
+// change between connections. Teams are counted by team name, so offices accumulate their
+// statistics across team mode sessions.
+type PlayerStats struct {
+	Rank     int    `json:"rank"`
+	Name     string `json:"name"`
+	Score    int    `json:"score"`
+	Correct  int    `json:"correct"`  // Number of correctly answered questions.
+	Sessions int    `json:"sessions"` // Number of sessions played.
+	Wins     int    `json:"wins"`     // Number of sessions finished with rank 1.
+}
+
+// Filter for querying quiz sessions from the store. Zero-valued fields are ignored.
+type SessionFilter struct {
+	Room  string
+	Since time.Time // Includes sessions that ended at or after this time.
+	Until time.Time // Includes sessions that ended before this time.
+	Limit int       // Maximum number of sessions to return, starting with the most recent.
+}
+
+// Opens the store's database at the given file path, creating it if it does not exist.
+// Returns error if the database could not be opened.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open quiz store at %v: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{sessionsBucket, questionUsageBucket} {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize quiz store: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Closes the store's database.
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Saves the given session to the quiz history, assigning it a new ID.
+// Returns error if writing to the database failed.
+func (store *Store) SaveSession(session Session) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionsBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+
+		value, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(sequenceKey(id), value)
+	})
+}
+
+// Returns the sessions in the quiz history matching the given filter, with the most recent first.
+// Returns error if reading from the database failed.
+func (store *Store) Sessions(filter SessionFilter) ([]Session, error) {
+	sessions := make([]Session, 0)
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(sessionsBucket).Cursor()
+
+		// Iterates backwards, since keys are ordered by increasing session ID.
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			var session Session
+			err := json.Unmarshal(value, &session)
+			if err != nil {
+				return fmt.Errorf("invalid stored session %v: %w", key, err)
+			}
+
+			if !filter.matches(session) {
+				continue
+			}
+
+			sessions = append(sessions, session)
+			if filter.Limit > 0 && len(sessions) >= filter.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return sessions, err
+}
+
+// Returns the all-time leaderboard for the sessions matching the given filter (ignoring its limit),
+// ranked by total score. Returns error if reading from the database failed.
+func (store *Store) Leaderboard(filter SessionFilter) ([]PlayerStats, error) {
+	filter.Limit = 0
+	sessions, err := store.Sessions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	statsByName := make(map[string]*PlayerStats)
+	for _, session := range sessions {
+		for _, standing := range session.Standings {
+			stats, ok := statsByName[standing.Name]
+			if !ok {
+				stats = &PlayerStats{Name: standing.Name}
+				statsByName[standing.Name] = stats
+			}
+
+			stats.Score += standing.Score
+			stats.Correct += standing.Correct
+			stats.Sessions++
+			if standing.Rank == 1 {
+				stats.Wins++
+			}
+		}
+	}
+
+	leaderboard := make([]PlayerStats, 0, len(statsByName))
+	for _, stats := range statsByName {
+		leaderboard = append(leaderboard, *stats)
+	}
+
+	sort.Slice(leaderboard, func(i int, j int) bool {
+		if leaderboard[i].Score != leaderboard[j].Score {
+			return leaderboard[i].Score > leaderboard[j].Score
+		}
+		return leaderboard[i].Name < leaderboard[j].Name
+	})
+
+	for i := range leaderboard {
+		if i > 0 && leaderboard[i].Score == leaderboard[i-1].Score {
+			leaderboard[i].Rank = leaderboard[i-1].Rank
+		} else {
+			leaderboard[i].Rank = i + 1
+		}
+	}
+
+	return leaderboard, nil
+}
+
+// Records that the question with the given ID was asked in the given room at the given time.
+// Returns error if writing to the database failed.
+func (store *Store) RecordQuestionAsked(room string, questionID int, askedAt time.Time) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(questionUsageBucket).CreateBucketIfNotExists([]byte(room))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(sequenceKey(uint64(questionID)), sequenceKey(uint64(askedAt.UnixNano())))
+	})
+}
+
+// Returns when each question was last asked in the given room, by question ID. Questions that have
+// not been asked since the room's usage was last reset are not included.
+// Returns error if reading from the database failed.
+func (store *Store) QuestionUsage(room string) (map[int]time.Time, error) {
+	usage := make(map[int]time.Time)
+
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(questionUsageBucket).Bucket([]byte(room))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(key []byte, value []byte) error {
+			if len(key) != 8 || len(value) != 8 {
+				return fmt.Errorf("invalid stored question usage %v", key)
+			}
+
+			questionID := int(binary.BigEndian.Uint64(key))
+			usage[questionID] = time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+			return nil
+		})
+	})
+
+	return usage, err
+}
+
+// Forgets which questions have been asked in the given room, so that all questions are considered
+// unasked. Returns error if writing to the database failed.
+func (store *Store) ResetQuestionUsage(room string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(questionUsageBucket).DeleteBucket([]byte(room))
+		if errors.Is(err, bolt.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Returns whether the given session matches the filter.
+func (filter SessionFilter) matches(session Session) bool {
+	if filter.Room != "" && session.Room != filter.Room {
+		return false
+	}
+
+	if !filter.Since.IsZero() && session.EndedAt.Before(filter.Since) {
+		return false
+	}
+
+	if !filter.Until.IsZero() && !session.EndedAt.Before(filter.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Encodes the given sequence number as a big-endian database key, so that keys sort in order.
+func sequenceKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
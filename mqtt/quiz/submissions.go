@@ -0,0 +1,57 @@
+package quiz
+
+import (
+	"errors"
+
+	"github.com/dcs-team4/coffeetalk/stm"
+)
+
+// Whether players can change their answer to a question after submitting it.
+type AnswerPolicy string
+
+const (
+	// Players can change their answer until the question's deadline. Their last answer counts.
+	AllowAnswerChanges AnswerPolicy = "change"
+
+	// A player's first answer to a question is final, and later answers are rejected.
+	LockFirstAnswer AnswerPolicy = "lock"
+)
+
+// Returned when a player answers a question again under the LockFirstAnswer policy.
+var errAnswerLocked = errors.New("answer already locked in")
+
+// Returns whether the answer policy is one of the supported policies, or empty.
+func (policy AnswerPolicy) valid() bool {
+	switch policy {
+	case "", AllowAnswerChanges, LockFirstAnswer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Records the answers that were received before the current question's deadline, but are still
+// waiting in the machine's submission channel, so that they count despite arriving at the last
+// moment.
+func (machine *QuizMachine) drainSubmissions(currentState stm.StateID) {
+	for {
+		select {
+		case submission := <-machine.submissions:
+			machine.handleAnswer(currentState, submission)
+		default:
+			return
+		}
+	}
+}
+
+// Publishes an acknowledgement of the given recorded answer to the reply topic of the client that
+// submitted it. Changed tells whether it replaced the client's previous answer.
+func (machine *QuizMachine) replyAnswerReceived(submission submission, changed bool) {
+	machine.messenger.Reply(submission.clientID, AnswerReceivedMessage{
+		Message:    newMessage(MsgAnswerReceived),
+		QuestionID: submission.message.QuestionID,
+		Answer:     submission.message.Answer,
+		Changed:    changed,
+		Final:      machine.answerPolicy == LockFirstAnswer,
+	})
+}
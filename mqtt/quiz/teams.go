@@ -0,0 +1,101 @@
+package quiz
+
+import (
+	"strings"
+)
+
+// How the answers of players on the same team are combined into the team's answer in team mode.
+type TeamMode string
+
+const (
+	// Players compete individually.
+	TeamsOff TeamMode = ""
+
+	// The first answer submitted by a member of the team counts.
+	TeamsFirst TeamMode = "first"
+
+	// The answer submitted by most members of the team counts. Ties are won by the answer that was
+	// submitted first.
+	TeamsMajority TeamMode = "majority"
+
+	// The answer of the team's captain (the first member to join the team) counts. Falls back to
+	// the first answer if the captain did not answer.
+	TeamsCaptain TeamMode = "captain"
+)
+
+// Prefix of the names that office clients join with, as set by the web app's office client.
+const officeNamePrefix = "Office "
+
+// Returns whether the team mode is one of the supported modes.
+func (mode TeamMode) valid() bool {
+	switch mode {
+	case TeamsOff, TeamsFirst, TeamsMajority, TeamsCaptain:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the key and display name of the team for the client with the given ID and name.
+// Players join the team with the given team name if set, otherwise office clients form teams by
+// their office name. Players without a team compete alone, as a team of one.
+func teamOf(clientID string, name string, teamName string) (key string, displayName string) {
+	teamName = strings.TrimSpace(teamName)
+	if teamName == "" && strings.HasPrefix(name, officeNamePrefix) {
+		teamName = name
+	}
+
+	if teamName == "" {
+		// Prefixes the key, so that a player's own team never clashes with a named team.
+		return "player:" + clientID, name
+	}
+
+	return "team:" + strings.ToLower(teamName), teamName
+}
+
+// Combines the given answers from members of a competitor into the competitor's answer, according
+// to the team mode. The members are the competitor's members in the order they joined.
+// Returns nil if there are no answers.
+func (mode TeamMode) combine(
+	answers []*memberAnswer, members []member, matching MatchConfig,
+) *memberAnswer {
+	if len(answers) == 0 {
+		return nil
+	}
+
+	switch mode {
+	case TeamsMajority:
+		return majorityAnswer(answers, matching)
+	case TeamsCaptain:
+		captain := members[0].clientID
+		for _, answer := range answers {
+			if answer.clientID == captain {
+				return answer
+			}
+		}
+	}
+
+	// Answers are ordered by when members first answered, so the first answer is the earliest.
+	return answers[0]
+}
+
+// Returns the answer submitted by most members, comparing answers after normalizing them with the
+// given matching rules. Ties are won by the answer that was submitted first.
+func majorityAnswer(answers []*memberAnswer, matching MatchConfig) *memberAnswer {
+	counts := make(map[string]int)
+	for _, answer := range answers {
+		counts[matching.normalize(answer.answer)]++
+	}
+
+	var majority *memberAnswer
+	majorityCount := 0
+	for _, answer := range answers {
+		count := counts[matching.normalize(answer.answer)]
+		if count > majorityCount {
+			majority = answer
+			majorityCount = count
+		}
+	}
+
+	return majority
+}
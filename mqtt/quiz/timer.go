@@ -0,0 +1,128 @@
+package quiz
+
+import (
+	"time"
+)
+
+// Timer for the quiz machine's states, which can be paused and resumed while preserving its
+// remaining time, and stopped early. Should only be used from the quiz machine's own goroutine.
+type quizTimer struct {
+	timer *time.Timer
+
+	// The time at which the timer was last started, for measuring elapsed time.
+	startedAt time.Time
+
+	// The time at which the timer expires, if running.
+	deadline time.Time
+
+	// Time left on the timer when it was paused, and the time at which it was paused.
+	remaining time.Duration
+	pausedAt  time.Time
+
+	// Total time the timer has been paused since it was last started, excluding the current pause.
+	pausedTime time.Duration
+
+	paused bool
+}
+
+// Returns a new quiz timer, stopped until started.
+func newQuizTimer() *quizTimer {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return &quizTimer{timer: timer}
+}
+
+// Returns a channel that receives when the timer expires.
+func (timer *quizTimer) expired() <-chan time.Time {
+	return timer.timer.C
+}
+
+// Starts the timer to expire after the given duration, discarding any previous expiry.
+func (timer *quizTimer) start(duration time.Duration) {
+	timer.stop()
+	timer.startedAt = time.Now()
+	timer.pausedTime = 0
+	timer.run(duration)
+}
+
+// Runs the timer to expire after the given duration. Expects the timer to be stopped.
+func (timer *quizTimer) run(duration time.Duration) {
+	timer.deadline = time.Now().Add(duration)
+	timer.timer.Reset(duration)
+}
+
+// Stops the timer, so that it does not expire until started again.
+func (timer *quizTimer) stop() {
+	if !timer.timer.Stop() {
+		// Drains the channel in case the timer expired before it was received.
+		select {
+		case <-timer.timer.C:
+		default:
+		}
+	}
+	timer.paused = false
+}
+
+// Shortens the timer to expire after the given duration, if it would otherwise expire later. Does
+// not affect the elapsed time, which is still measured from when the timer was started. Returns
+// false if the timer is paused, or already expires sooner.
+func (timer *quizTimer) shorten(duration time.Duration) bool {
+	if duration < 0 {
+		duration = 0
+	}
+
+	if timer.paused || time.Until(timer.deadline) <= duration {
+		return false
+	}
+
+	timer.stop()
+	timer.run(duration)
+	return true
+}
+
+// Pauses the timer, storing its remaining time. Returns false if the timer was already paused.
+func (timer *quizTimer) pause() bool {
+	if timer.paused {
+		return false
+	}
+
+	timer.remaining = time.Until(timer.deadline)
+	if timer.remaining < 0 {
+		timer.remaining = 0
+	}
+
+	timer.stop()
+	timer.paused = true
+	timer.pausedAt = time.Now()
+	return true
+}
+
+// Resumes the timer with its remaining time from when it was paused.
+// Returns false if the timer was not paused.
+func (timer *quizTimer) resume() bool {
+	if !timer.paused {
+		return false
+	}
+
+	timer.paused = false
+	timer.pausedTime += time.Since(timer.pausedAt)
+	timer.run(timer.remaining)
+	return true
+}
+
+// Returns the time the timer has been running since it was last started, excluding paused time, up
+// to when it expires.
+func (timer *quizTimer) elapsed() time.Duration {
+	end := time.Now()
+	if timer.paused {
+		end = timer.pausedAt
+	} else if end.After(timer.deadline) {
+		end = timer.deadline
+	}
+
+	elapsed := end.Sub(timer.startedAt) - timer.pausedTime
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
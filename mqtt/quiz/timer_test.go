@@ -0,0 +1,132 @@
+package quiz
+
+import (
+	"testing"
+	"time"
+)
+
+// Allowed difference between measured and expected durations, for the time passing while a test
+// runs.
+const timerTolerance = 100 * time.Millisecond
+
+// Returns whether the given durations are within timerTolerance of each other.
+func closeTo(got time.Duration, want time.Duration) bool {
+	difference := got - want
+	return difference > -timerTolerance && difference < timerTolerance
+}
+
+func TestQuizTimerElapsed(t *testing.T) {
+	now := time.Now()
+	ago := func(seconds int) time.Time { return now.Add(-time.Duration(seconds) * time.Second) }
+
+	tests := []struct {
+		name  string
+		timer quizTimer
+		want  time.Duration
+	}{
+		{
+			"running",
+			quizTimer{startedAt: ago(10), deadline: ago(-20)},
+			10 * time.Second,
+		},
+		{
+			"resumed",
+			quizTimer{startedAt: ago(10), deadline: ago(-20), pausedTime: 4 * time.Second},
+			6 * time.Second,
+		},
+		{
+			"paused",
+			quizTimer{
+				startedAt: ago(10), pausedAt: ago(2), pausedTime: 3 * time.Second, paused: true,
+			},
+			5 * time.Second,
+		},
+		{
+			"expired",
+			quizTimer{startedAt: ago(10), deadline: ago(3)},
+			7 * time.Second,
+		},
+		{
+			"shortened and expired after pause",
+			quizTimer{startedAt: ago(10), deadline: ago(1), pausedTime: 2 * time.Second},
+			7 * time.Second,
+		},
+		{
+			"more paused than started",
+			quizTimer{startedAt: ago(1), deadline: ago(-20), pausedTime: 5 * time.Second},
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.timer.elapsed(); !closeTo(got, test.want) {
+			t.Errorf("%v: elapsed() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestQuizTimerControls(t *testing.T) {
+	timer := newQuizTimer()
+	timer.start(time.Hour)
+
+	steps := []struct {
+		name          string
+		run           func() bool
+		want          bool
+		wantPaused    bool
+		wantRemaining time.Duration // Time until the deadline, or remaining time if paused.
+	}{
+		{"pause", timer.pause, true, true, time.Hour},
+		{"pause again", timer.pause, false, true, time.Hour},
+		{"shorten while paused", func() bool {
+			return timer.shorten(time.Minute)
+		}, false, true, time.Hour},
+		{"resume", timer.resume, true, false, time.Hour},
+		{"resume again", timer.resume, false, false, time.Hour},
+		{"lengthen", func() bool {
+			return timer.shorten(2 * time.Hour)
+		}, false, false, time.Hour},
+		{"shorten", func() bool {
+			return timer.shorten(time.Minute)
+		}, true, false, time.Minute},
+		{"shorten to negative", func() bool {
+			return timer.shorten(-time.Minute)
+		}, true, false, 0},
+	}
+
+	for _, step := range steps {
+		if got := step.run(); got != step.want {
+			t.Errorf("%v: got %v, want %v", step.name, got, step.want)
+		}
+		if timer.paused != step.wantPaused {
+			t.Errorf("%v: paused = %v, want %v", step.name, timer.paused, step.wantPaused)
+		}
+
+		remaining := time.Until(timer.deadline)
+		if timer.paused {
+			remaining = timer.remaining
+		}
+		if !closeTo(remaining, step.wantRemaining) {
+			t.Errorf("%v: remaining %v, want %v", step.name, remaining, step.wantRemaining)
+		}
+	}
+
+	select {
+	case <-timer.expired():
+	case <-time.After(time.Second):
+		t.Error("timer shortened to 0 did not expire")
+	}
+}
+
+func TestQuizTimerStop(t *testing.T) {
+	timer := newQuizTimer()
+	timer.start(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	timer.stop()
+
+	select {
+	case <-timer.expired():
+		t.Error("stopped timer expired")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
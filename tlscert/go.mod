@@ -0,0 +1,3 @@
+module github.com/dcs-team4/coffeetalk/tlscert
+
+go 1.18
@@ -0,0 +1,185 @@
+// Package tlscert provides a TLS certificate type for the CoffeeTalk servers, read from files given
+// at runtime and reloaded when they change, so that renewed certificates are served without
+// rebuilding or restarting the servers.
+package tlscert
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Paths of the certificate and key files in the embedded file system given to Load, used if no
+// file paths are given.
+const (
+	EmbeddedCertificatePath = "tls/tls-cert.pem"
+	EmbeddedKeyPath         = "tls/tls-key.pem"
+)
+
+// How often Certificate.Watch checks the certificate and key files for changes.
+const checkInterval = time.Minute
+
+// TLS certificate and private key, read from files given at runtime, or from files embedded in the
+// server if no paths are given. New connections use the latest loaded certificate, while existing
+// connections keep the one they were established with.
+type Certificate struct {
+	// Paths to the PEM-encoded certificate and key files. Empty if using the embedded files.
+	certificatePath string
+	keyPath         string
+
+	// File system with the certificate and key files at EmbeddedCertificatePath and
+	// EmbeddedKeyPath, used if no paths are given.
+	embedded fs.FS
+
+	// Guards certificate.
+	lock sync.RWMutex
+
+	// The currently served certificate.
+	certificate *tls.Certificate
+}
+
+// Loads the TLS certificate and key from the files at the given paths, or from the given embedded
+// file system (see EmbeddedCertificatePath and EmbeddedKeyPath) if both paths are empty. Returns
+// error if only one path is given, or the files are missing or invalid.
+func Load(certificatePath string, keyPath string, embedded fs.FS) (*Certificate, error) {
+	if (certificatePath == "") != (keyPath == "") {
+		return nil, errors.New("tls setup failed: certificate and key paths must be given together")
+	}
+
+	certificate := &Certificate{
+		certificatePath: certificatePath,
+		keyPath:         keyPath,
+		embedded:        embedded,
+	}
+	err := certificate.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return certificate, nil
+}
+
+// Reads the certificate and key files again, and serves the new certificate to new connections.
+// Returns error if the files are missing or invalid, in which case the previous certificate is
+// kept.
+func (certificate *Certificate) Reload() error {
+	certificateFile, keyFile, err := certificate.readFiles()
+	if err != nil {
+		return err
+	}
+
+	keyPair, err := tls.X509KeyPair(certificateFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("error configuring tls certificate: %w", err)
+	}
+
+	certificate.lock.Lock()
+	certificate.certificate = &keyPair
+	certificate.lock.Unlock()
+
+	return nil
+}
+
+// Reads the certificate's certificate and key files, or the embedded files if it has no paths.
+// Returns error if either is missing.
+func (certificate *Certificate) readFiles() (certificateFile []byte, keyFile []byte, err error) {
+	readFile := os.ReadFile
+	certificatePath, keyPath := certificate.certificatePath, certificate.keyPath
+	if certificatePath == "" {
+		if certificate.embedded == nil {
+			return nil, nil, errors.New("tls setup failed: no certificate files given")
+		}
+
+		readFile = func(path string) ([]byte, error) {
+			return fs.ReadFile(certificate.embedded, path)
+		}
+		certificatePath, keyPath = EmbeddedCertificatePath, EmbeddedKeyPath
+	}
+
+	certificateFile, err = readFile(certificatePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls certificate setup failed: %w", err)
+	}
+
+	keyFile, err = readFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls key setup failed: %w", err)
+	}
+
+	return certificateFile, keyFile, nil
+}
+
+// Reloads the certificate whenever its certificate or key file changes (checked every minute), and
+// whenever the process receives SIGHUP. Keeps the previous certificate if reloading fails. Runs
+// until the process exits, so should be called in a new goroutine. Returns immediately if the
+// certificate uses the embedded files, which never change.
+func (certificate *Certificate) Watch() {
+	if certificate.certificatePath == "" {
+		return
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	lastModified := certificate.lastModified()
+	for {
+		select {
+		case <-hangup:
+			log.Println("Received SIGHUP, reloading TLS certificate...")
+		case <-ticker.C:
+			modified := certificate.lastModified()
+			if modified.Equal(lastModified) {
+				continue
+			}
+			lastModified = modified
+			log.Println("TLS certificate files changed, reloading...")
+		}
+
+		err := certificate.Reload()
+		if err != nil {
+			log.Println("Failed to reload TLS certificate, keeping previous:", err)
+		} else {
+			log.Println("TLS certificate reloaded.")
+		}
+	}
+}
+
+// Returns the latest modification time of the certificate's certificate and key files. Returns the
+// zero time if either file is missing.
+func (certificate *Certificate) lastModified() time.Time {
+	var lastModified time.Time
+	for _, path := range []string{certificate.certificatePath, certificate.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+
+		if info.ModTime().After(lastModified) {
+			lastModified = info.ModTime()
+		}
+	}
+
+	return lastModified
+}
+
+// Returns a TLS config that serves the certificate's currently loaded certificate to each new
+// connection.
+func (certificate *Certificate) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certificate.lock.RLock()
+			defer certificate.lock.RUnlock()
+			return certificate.certificate, nil
+		},
+	}
+}
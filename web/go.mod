@@ -1,3 +1,8 @@
 module github.com/dcs-team4/coffeetalk/web
 
 go 1.18
+
+require github.com/dcs-team4/coffeetalk/tlscert v0.0.0
+
+// Shared module in this repository, not published separately.
+replace github.com/dcs-team4/coffeetalk/tlscert => ../tlscert
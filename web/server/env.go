@@ -37,7 +37,7 @@ func makeBaseClientEnv(envNames []string) map[string]string {
 }
 
 // Returns a map of environment variables, using the variables from baseClientEnv,
-// and adding extra environment variables passed as arguments.
+// and adding extra environment variables passed as arguments, and an MQTT token if configured.
 func makeClientEnv(clientType string) map[string]string {
 	env := make(map[string]string)
 
@@ -45,6 +45,7 @@ func makeClientEnv(clientType string) map[string]string {
 		env[key] = value
 	}
 	env[envClientType] = clientType
+	addMQTTToken(env)
 
 	return env
 }
@@ -1,38 +1,43 @@
 package server
 
 import (
-	"crypto/tls"
 	"embed"
-	"fmt"
 	"net/http"
+	"os"
+
+	"github.com/dcs-team4/coffeetalk/tlscert"
 )
 
-// For production environment: expects tls-cert.pem and tls-key.pem in tls directory.
+// For production environment without configured TLS file paths: expects tls-cert.pem and
+// tls-key.pem in tls directory.
+//
 //go:embed all:tls
 var tlsFiles embed.FS
 
-// Listens and serves on the given address, using embedded TLS certificate and key files.
+// Environment variables with the paths to the PEM-encoded TLS certificate and key files. Always
+// set by docker-compose-prod.yml, to the files in TLS_DIR. If not set, such as when testing TLS
+// locally without it, the embedded TLS files are used.
+const (
+	envTLSCertificate = "TLS_CERT_FILE"
+	envTLSKey         = "TLS_KEY_FILE"
+)
+
+// Listens and serves on the given address, using the TLS certificate and key files given in the
+// environment, or the embedded files if none are given. Reloads the certificate whenever the files
+// change or the process receives SIGHUP, without dropping existing connections.
 // If handler is nil, uses http.DefaultServeMux.
 func listenAndServeTLS(address string, handler http.Handler) error {
-	certFile, err := tlsFiles.ReadFile("tls/tls-cert.pem")
-	if err != nil {
-		return fmt.Errorf("tls certificate setup failed: %w", err)
-	}
-
-	keyFile, err := tlsFiles.ReadFile("tls/tls-key.pem")
+	certificate, err := tlscert.Load(os.Getenv(envTLSCertificate), os.Getenv(envTLSKey), tlsFiles)
 	if err != nil {
-		return fmt.Errorf("tls key setup failed: %w", err)
+		return err
 	}
 
-	certificate, err := tls.X509KeyPair(certFile, keyFile)
-	if err != nil {
-		return fmt.Errorf("error configuring tls certificate: %w", err)
-	}
+	go certificate.Watch()
 
 	server := &http.Server{
 		Addr:      address,
 		Handler:   handler,
-		TLSConfig: &tls.Config{Certificates: []tls.Certificate{certificate}},
+		TLSConfig: certificate.TLSConfig(),
 	}
 
 	return server.ListenAndServeTLS("", "")
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Server environment variable with the secret shared with the MQTT broker, for signing the tokens
+// that web app clients authenticate with. Clients get no token if it is not set.
+const envMQTTTokenSecret = "MQTT_TOKEN_SECRET"
+
+// Client environment variables with the MQTT username and token to connect to the broker with.
+const (
+	envMQTTUsername = "MQTT_USERNAME"
+	envMQTTToken    = "MQTT_TOKEN"
+)
+
+// Username of web app clients on the MQTT broker.
+const mqttUsername = "web"
+
+// How long MQTT tokens are valid for. Clients only need them to connect, so they are short-lived,
+// and renewed whenever a page is loaded.
+const mqttTokenLifetime = 10 * time.Minute
+
+// Returns a JSON Web Token for the given MQTT username, signed with HMAC-SHA256 using the given
+// secret, and expiring after the given lifetime.
+func signMQTTToken(secret []byte, username string, lifetime time.Duration) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"sub": username,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(lifetime).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Adds a freshly signed MQTT username and token to the given client environment, if the server is
+// configured with a token secret.
+func addMQTTToken(env map[string]string) {
+	secret, ok := os.LookupEnv(envMQTTTokenSecret)
+	if !ok || secret == "" {
+		return
+	}
+
+	env[envMQTTUsername] = mqttUsername
+	env[envMQTTToken] = signMQTTToken([]byte(secret), mqttUsername, mqttTokenLifetime)
+}
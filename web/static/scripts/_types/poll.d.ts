@@ -0,0 +1,68 @@
+/** Type declarations for JSON messages as defined by the MQTT poll server. */
+declare namespace poll {
+  type MessageTypes = {
+    START: "start-poll";
+    CLOSE: "close-poll";
+    VOTE: "vote";
+    POLL: "poll";
+    ERROR: "error";
+  };
+
+  /** Messages that the poll server expects the client to receive. */
+  type ReceivableMessage = PollMessage | ErrorMessage;
+
+  type Message = {
+    version: number;
+  };
+
+  /** Start message for a poll, posted on the poll status topic. */
+  type StartPollMessage = Message & {
+    type: MessageTypes["START"];
+    question: string;
+    options: string[];
+    /** Number of seconds the poll is open for. Uses the server's default if unset. */
+    duration?: number;
+  };
+
+  /** Closes the given poll before it times out. Only allowed for the poll's creator. */
+  type ClosePollMessage = Message & {
+    type: MessageTypes["CLOSE"];
+    pollId: number;
+  };
+
+  /** Vote in the given poll, replacing any previous vote by the client. */
+  type VoteMessage = Message & {
+    type: MessageTypes["VOTE"];
+    pollId: number;
+    /** Index of the chosen option, starting at 0. */
+    option: number;
+  };
+
+  /** Tally of the current poll, retained by the server so that late joiners see it. */
+  type PollMessage = Message & {
+    type: MessageTypes["POLL"];
+    pollId: number;
+    question: string;
+    options: OptionTally[];
+    /** Total number of votes. */
+    votes: number;
+    open: boolean;
+    /** Client ID of the client that started the poll. */
+    creator: string;
+    /** Unix timestamp (in milliseconds) of when the poll closes. Omitted once closed. */
+    deadline?: number;
+  };
+
+  type OptionTally = {
+    option: string;
+    votes: number;
+  };
+
+  /** Sent on a client's reply topic when the server rejects a message from the client. */
+  type ErrorMessage = Message & {
+    type: MessageTypes["ERROR"];
+    /** Type of the rejected message. */
+    request: string;
+    error: string;
+  };
+}
@@ -0,0 +1,163 @@
+/** Type declarations for JSON messages as defined by the MQTT quiz server. */
+declare namespace quiz {
+  type MessageTypes = {
+    START: "start-quiz";
+    END: "end-quiz";
+    PAUSE: "pause-quiz";
+    RESUME: "resume-quiz";
+    SKIP: "skip-question";
+    ABORT: "abort-quiz";
+    QUESTION: "question";
+    ANSWER: "answer";
+    STATE: "state";
+    ERROR: "error";
+    ANSWER_RECEIVED: "answer-received";
+    SUBMIT_ANSWER: "submit-answer";
+  };
+
+  /** Messages that the quiz server expects the client to receive. */
+  type ReceivableMessage =
+    | QuestionMessage
+    | AnswerMessage
+    | StatusMessage
+    | StateMessage
+    | ErrorMessage
+    | AnswerReceivedMessage;
+
+  type Message = {
+    version: number;
+  };
+
+  type QuestionMessage = Message & {
+    type: MessageTypes["QUESTION"];
+    questionId: number;
+    question: string;
+    /** Number of the question in the quiz session, starting at 1. */
+    index: number;
+    /** Number of questions in the quiz session. */
+    total: number;
+    /** Language tag of the question text, such as "en" or "nb". */
+    language: string;
+    /** Game mode of the quiz session: "trivia", "estimation", "would-you-rather" or "association". */
+    mode: string;
+    /** Options to choose between. Only set for questions with options. */
+    options?: string[];
+    /** Image or audio clip to show with the question. */
+    media?: Media;
+    /** Unix timestamp (in milliseconds) of when the answer will be revealed. */
+    deadline: number;
+  };
+
+  type AnswerMessage = Message & {
+    type: MessageTypes["ANSWER"];
+    questionId: number;
+    question: string;
+    answer: string;
+    index: number;
+    total: number;
+    language: string;
+    media?: Media;
+    /** Number of players or teams giving each answer. Only set by game modes that reveal it. */
+    tally?: TallyEntry[];
+    /** Unix timestamp (in milliseconds) of when the next question is asked, or the quiz ends. */
+    deadline: number;
+  };
+
+  type StatusMessage = Message & {
+    type: MessageTypes["START" | "END" | "PAUSE" | "RESUME" | "SKIP" | "ABORT"];
+  };
+
+  /** Current state of the quiz session, retained by the server so that late joiners see it. */
+  type StateMessage = Message & {
+    type: MessageTypes["STATE"];
+    state: "idle" | "question" | "answer";
+    paused: boolean;
+    /** The current question. Omitted when idle. */
+    questionId?: number;
+    question?: string;
+    /** The answer to the current question. Only set in the answer state. */
+    answer?: string;
+    index: number;
+    total: number;
+    language?: string;
+    mode?: string;
+    options?: string[];
+    media?: Media;
+    tally?: TallyEntry[];
+    /** Unix timestamp (in milliseconds) of when the current state ends. Omitted when paused. */
+    deadline?: number;
+    /** Time (in milliseconds) left of the current state while paused. */
+    remaining?: number;
+    scoring?: string;
+    teamMode?: string;
+    /**
+     * Client ID of the quiz host, the only client (besides admins) allowed to control the quiz.
+     * Empty if the quiz has no host, in which case the next client to answer becomes host.
+     */
+    host?: string;
+    standings?: Standing[];
+  };
+
+  /** Sent on a client's reply topic when the server rejects a message from the client. */
+  type ErrorMessage = Message & {
+    type: MessageTypes["ERROR"];
+    /** Type of the rejected message. */
+    request: string;
+    error: string;
+  };
+
+  /** Sent on a client's reply topic when the server records the client's answer to a question. */
+  type AnswerReceivedMessage = Message & {
+    type: MessageTypes["ANSWER_RECEIVED"];
+    questionId: number;
+    answer: string;
+    /** Whether the answer replaced the client's previous answer to the question. */
+    changed: boolean;
+    /** Whether the answer is final, as the server does not allow changing answers. */
+    final: boolean;
+  };
+
+  /** Media of a question, fetched from the quiz server's HTTP API. */
+  type Media = {
+    url: string;
+    type: "image" | "audio";
+  };
+
+  type TallyEntry = {
+    answer: string;
+    count: number;
+  };
+
+  type Standing = {
+    rank: number;
+    /** Omitted for teams. */
+    clientId?: string;
+    name: string;
+    score: number;
+    correct: number;
+    /** Names of the team's players. Omitted for individual players. */
+    members?: string[];
+  };
+
+  /** Answer to the current question, posted on the submissions topic. */
+  type SubmitAnswerMessage = Message & {
+    type: MessageTypes["SUBMIT_ANSWER"];
+    questionId: number;
+    answer: string;
+    /** Display name of the player, used in the leaderboard. */
+    name: string;
+  };
+
+  /** Start message with options for the quiz session. */
+  type StartQuizMessage = Message & {
+    type: MessageTypes["START"];
+    /** Name of the scoring strategy: "classic", "timed", "streak" or "timed-streak". */
+    scoring?: string;
+    /** How answers are combined per team: "first", "majority" or "captain". Individual if unset. */
+    teamMode?: string;
+    /** Language tag of the questions, such as "nb". Uses the room's language if unset. */
+    language?: string;
+    /** Name of the game mode. Uses the room's game mode if unset. */
+    mode?: string;
+  };
+}
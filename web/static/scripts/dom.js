@@ -1,5 +1,6 @@
 import { setUsername } from "./user.js";
-import { startQuiz } from "./mqtt.js";
+import { startQuiz, submitAnswer } from "./mqtt.js";
+import { startPoll, closePoll } from "./poll.js";
 import { joinStream, leaveStream } from "./stream.js";
 
 /**
@@ -19,9 +20,27 @@ export const DOM = Object.freeze({
   quizQuestionContainer: () =>
     /** @type {HTMLElement} */ (document.getElementById("quiz-question-container")),
   quizQuestion: () => /** @type {HTMLElement} */ (document.getElementById("quiz-question")),
+  quizMedia: () => /** @type {HTMLElement} */ (document.getElementById("quiz-media")),
   quizAnswerContainer: () =>
     /** @type {HTMLElement} */ (document.getElementById("quiz-answer-container")),
   quizAnswer: () => /** @type {HTMLElement} */ (document.getElementById("quiz-answer")),
+  quizSubmitForm: () => /** @type {HTMLElement} */ (document.getElementById("quiz-submit-form")),
+  quizAnswerInput: () =>
+    /** @type {HTMLInputElement} */ (document.getElementById("quiz-answer-input")),
+  submitAnswerButton: () =>
+    /** @type {HTMLButtonElement} */ (document.getElementById("submit-answer")),
+  quizAnswerStatus: () =>
+    /** @type {HTMLElement} */ (document.getElementById("quiz-answer-status")),
+  pollForm: () => /** @type {HTMLElement} */ (document.getElementById("poll-form")),
+  pollQuestionInput: () =>
+    /** @type {HTMLInputElement} */ (document.getElementById("poll-question-input")),
+  pollOptionsInput: () =>
+    /** @type {HTMLInputElement} */ (document.getElementById("poll-options-input")),
+  startPollButton: () => /** @type {HTMLElement} */ (document.getElementById("start-poll")),
+  pollView: () => /** @type {HTMLElement} */ (document.getElementById("poll-view")),
+  pollTitle: () => /** @type {HTMLElement} */ (document.getElementById("poll-title")),
+  pollOptions: () => /** @type {HTMLElement} */ (document.getElementById("poll-options")),
+  closePollButton: () => /** @type {HTMLElement} */ (document.getElementById("close-poll")),
   leaveStreamBar: () => /** @type {?HTMLElement} */ (document.getElementById("leave-stream-bar")),
   leaveStreamButton: () => /** @type {?HTMLElement} */ (document.getElementById("leave-stream")),
   videos: () => /** @type {HTMLElement} */ (document.getElementById("videos")),
@@ -39,6 +58,14 @@ export function registerListeners() {
   DOM.joinCallButton()?.addEventListener("click", joinStream);
   DOM.leaveStreamButton()?.addEventListener("click", leaveStream);
   DOM.startQuizButton().addEventListener("click", startQuiz);
+  DOM.submitAnswerButton().addEventListener("click", submitAnswer);
+  DOM.quizAnswerInput().addEventListener("keydown", (event) => {
+    if (event.key === "Enter") {
+      submitAnswer();
+    }
+  });
+  DOM.startPollButton().addEventListener("click", startPoll);
+  DOM.closePollButton().addEventListener("click", closePoll);
 }
 
 /**
@@ -1,5 +1,7 @@
 import { env } from "./env.js";
 import { DOM } from "./dom.js";
+import { getUsername } from "./user.js";
+import { handlePollMessage, isPollTopic, pollSubscriptions } from "./poll.js";
 
 /** Shared prefix for MQTT quiz topics. */
 const MQTT_TOPIC_PREFIX = "coffeetalk/quiz";
@@ -12,17 +14,37 @@ const mqttTopics = {
   QUESTIONS: `${MQTT_TOPIC_PREFIX}/questions`,
   ANSWERS: `${MQTT_TOPIC_PREFIX}/answers`,
   STATUS: `${MQTT_TOPIC_PREFIX}/status`,
+  LEADERBOARD: `${MQTT_TOPIC_PREFIX}/leaderboard`,
+  RESULTS: `${MQTT_TOPIC_PREFIX}/results`,
+  STATE: `${MQTT_TOPIC_PREFIX}/state`,
+  SUBMISSIONS: `${MQTT_TOPIC_PREFIX}/submissions`,
+  /** Prefix of the topics where the server replies to individual clients, by client ID. */
+  REPLIES: `${MQTT_TOPIC_PREFIX}/replies/`,
 };
 
 /**
- * MQTT quiz messages that the server expects.
+ * MQTT quiz message types that the server expects.
  * Should be updated if the quiz server message configuration changes.
+ * @type {quiz.MessageTypes}
  */
 const mqttMessages = {
   START: "start-quiz",
   END: "end-quiz",
+  PAUSE: "pause-quiz",
+  RESUME: "resume-quiz",
+  SKIP: "skip-question",
+  ABORT: "abort-quiz",
+  QUESTION: "question",
+  ANSWER: "answer",
+  STATE: "state",
+  ERROR: "error",
+  ANSWER_RECEIVED: "answer-received",
+  SUBMIT_ANSWER: "submit-answer",
 };
 
+/** Version of the quiz server's JSON message format that this client understands. */
+const MQTT_MESSAGE_VERSION = 1;
+
 /**
  * Connection to the MQTT broker.
  * Undefined if uninitialized.
@@ -30,10 +52,22 @@ const mqttMessages = {
  */
 let mqttClient;
 
+/**
+ * ID of the question currently open for answers.
+ * Undefined if no question is open.
+ * @type {number | undefined}
+ */
+let openQuestionID;
+
+/**
+ * MQTT client ID of this client. Generated by the client rather than the server, so that the
+ * client knows its ID when the quiz server replies to it or names it as quiz host.
+ */
+export const mqttClientID = `web-${Math.random().toString(36).slice(2, 12)}`;
+
 /** Connects to the MQTT broker and sets up message listeners. */
 export function connectMQTT() {
-  // Passes "" as MQTT client ID, as that tells the server to generate a unique ID for us.
-  mqttClient = new Paho.MQTT.Client(env.MQTT_HOST, parseInt(env.MQTT_PORT), "");
+  mqttClient = new Paho.MQTT.Client(env.MQTT_HOST, parseInt(env.MQTT_PORT), mqttClientID);
   mqttClient.onMessageArrived = handleMQTTMessage;
   mqttClient.onConnectionLost = ({ errorCode, errorMessage }) => {
     // Error code 0 means no error.
@@ -45,12 +79,28 @@ export function connectMQTT() {
   mqttClient.connect({
     onSuccess: () => {
       console.log("Successfully connected to MQTT broker.");
-      mqttClient?.subscribe(`${MQTT_TOPIC_PREFIX}/#`);
+      // Only the client's own reply topic is subscribed to, as the broker denies access to the
+      // reply topics of other clients.
+      const topics = [
+        mqttTopics.STATE,
+        mqttTopics.QUESTIONS,
+        mqttTopics.ANSWERS,
+        mqttTopics.STATUS,
+        mqttTopics.LEADERBOARD,
+        mqttTopics.RESULTS,
+        `${mqttTopics.REPLIES}${mqttClientID}`,
+        ...pollSubscriptions(),
+      ];
+      for (const topic of topics) {
+        mqttClient?.subscribe(topic);
+      }
     },
     onFailure: ({ errorMessage }) => {
       console.log("Failed to connect to MQTT broker:", errorMessage);
     },
     useSSL: env.ENV === "production",
+    // The token is signed by the web server when serving the page, if the broker requires auth.
+    ...(env.MQTT_TOKEN ? { userName: env.MQTT_USERNAME, password: env.MQTT_TOKEN } : {}),
   });
 }
 
@@ -64,36 +114,54 @@ function handleMQTTMessage(message) {
     message: message.payloadString,
   });
 
-  switch (message.destinationName) {
-    case mqttTopics.QUESTIONS:
-      DOM.quizQuestion().innerText = message.payloadString;
-      DOM.quizAnswer().innerText = "";
+  if (isPollTopic(message.destinationName)) {
+    handlePollMessage(message.destinationName, message.payloadString);
+    return;
+  }
+
+  // Empty payloads are sent by the server when clearing retained messages, and carry no content.
+  if (message.payloadString === "") {
+    return;
+  }
 
-      // Initializes quiz view on receiving question rather than receiving start message,
-      // to allow users to join after the start message.
-      DOM.startQuizButton().classList.add("hide");
-      DOM.quizTitle().classList.remove("hide");
-      DOM.quizQuestionContainer().classList.remove("hide");
-      DOM.quizAnswerContainer().classList.remove("hide");
+  const quizMessage = parseQuizMessage(message.payloadString);
 
+  switch (message.destinationName) {
+    case mqttTopics.STATE:
+      if (quizMessage?.type === mqttMessages.STATE) {
+        showQuizState(quizMessage);
+      }
+      break;
+    // JSON messages on the question, answer and status topics are also described by the state
+    // topic, which is the authority on what to show. Only plain-text messages, as sent by servers
+    // in compatibility mode, are handled here.
+    case mqttTopics.QUESTIONS:
+      if (!quizMessage) {
+        showQuizView();
+        DOM.quizQuestion().innerText = message.payloadString;
+        DOM.quizAnswer().innerText = "";
+      }
       break;
     case mqttTopics.ANSWERS:
-      DOM.quizAnswer().innerText = message.payloadString;
+      if (!quizMessage) {
+        DOM.quizAnswer().innerText = message.payloadString;
+      }
       break;
     case mqttTopics.STATUS:
-      switch (message.payloadString) {
-        case mqttMessages.START:
-          break;
-        case mqttMessages.END:
-          DOM.quizTitle().classList.add("hide");
-          DOM.quizQuestionContainer().classList.add("hide");
-          DOM.quizQuestion().innerText = "";
-          DOM.quizAnswerContainer().classList.add("hide");
-          DOM.quizAnswer().innerText = "";
-          DOM.startQuizButton().classList.remove("hide");
-          break;
-        default:
-          console.log("Unrecognized MQTT status message:", message.payloadString);
+      if (!quizMessage && message.payloadString === mqttMessages.END) {
+        hideQuizView();
+      }
+      break;
+    case mqttTopics.LEADERBOARD:
+    case mqttTopics.RESULTS:
+      break;
+    case `${mqttTopics.REPLIES}${mqttClientID}`:
+      if (quizMessage?.type === mqttMessages.ERROR) {
+        console.log(`Quiz server rejected '${quizMessage.request}':`, quizMessage.error);
+        const rejected = quizMessage.request === mqttMessages.SUBMIT_ANSWER ? "Answer" : "Request";
+        DOM.quizAnswerStatus().innerText = `${rejected} rejected: ${quizMessage.error}`;
+      } else if (quizMessage?.type === mqttMessages.ANSWER_RECEIVED) {
+        showAnswerReceived(quizMessage);
       }
       break;
     default:
@@ -101,6 +169,148 @@ function handleMQTTMessage(message) {
   }
 }
 
+/**
+ * Shows the given quiz session state, as published by the server to all clients.
+ * @param {quiz.StateMessage} state
+ */
+function showQuizState(state) {
+  if (state.state === "idle") {
+    hideQuizView();
+    return;
+  }
+
+  showQuizView();
+  const pausedSuffix = state.paused ? " (paused)" : "";
+  const hostSuffix = state.host === mqttClientID ? " - you are host" : "";
+  DOM.quizTitle().innerText = `Quiz (${state.index}/${state.total})${pausedSuffix}${hostSuffix}`;
+  const options = state.options ? `\n${state.options.join(" / ")}` : "";
+  DOM.quizQuestion().innerText = `${state.question ?? ""}${options}`;
+  const tally = state.tally?.map((entry) => `${entry.answer}: ${entry.count}`).join(", ");
+  DOM.quizAnswer().innerText = tally ? `${state.answer ?? ""} (${tally})` : state.answer ?? "";
+  showQuizMedia(state.media);
+  showSubmitForm(state);
+}
+
+/**
+ * Shows the form for answering the current question while it is open for answers, and hides it
+ * otherwise. Clears the answer and its status when a new question is asked.
+ * @param {quiz.StateMessage} state
+ */
+function showSubmitForm(state) {
+  const open = state.state === "question" && !state.paused ? state.questionId : undefined;
+  if (open !== undefined && open !== openQuestionID) {
+    DOM.quizAnswerInput().value = "";
+    DOM.quizAnswerInput().disabled = false;
+    DOM.quizAnswerStatus().innerText = "";
+  }
+  openQuestionID = open;
+  DOM.quizSubmitForm().classList.toggle("hide", state.state !== "question");
+  DOM.submitAnswerButton().disabled = open === undefined || DOM.quizAnswerInput().disabled;
+}
+
+/**
+ * Shows that the server recorded this client's answer, and locks the answer input if the answer is
+ * final.
+ * @param {quiz.AnswerReceivedMessage} received
+ */
+function showAnswerReceived(received) {
+  if (received.questionId !== openQuestionID) {
+    return;
+  }
+
+  const suffix = received.final ? " (final)" : received.changed ? " (changed)" : "";
+  DOM.quizAnswerStatus().innerText = `Answer received: ${received.answer}${suffix}`;
+  if (received.final) {
+    DOM.quizAnswerInput().disabled = true;
+    DOM.submitAnswerButton().disabled = true;
+  }
+}
+
+/**
+ * Shows the given image or audio clip of the current question, or hides the media if undefined.
+ * Keeps the element if the media is unchanged, so that audio is not restarted by state updates.
+ * @param {quiz.Media | undefined} media
+ */
+function showQuizMedia(media) {
+  const container = DOM.quizMedia();
+  if (!media) {
+    container.replaceChildren();
+    delete container.dataset.url;
+    container.classList.add("hide");
+    return;
+  }
+
+  if (container.dataset.url === media.url) {
+    return;
+  }
+  container.dataset.url = media.url;
+
+  if (media.type === "audio") {
+    const audio = document.createElement("audio");
+    audio.src = media.url;
+    audio.controls = true;
+    audio.autoplay = true;
+    container.replaceChildren(audio);
+  } else {
+    const image = document.createElement("img");
+    image.src = media.url;
+    image.alt = "Quiz question image";
+    container.replaceChildren(image);
+  }
+  container.classList.remove("hide");
+}
+
+/** Shows the quiz question and answer, and hides the start button. */
+function showQuizView() {
+  DOM.startQuizButton().classList.add("hide");
+  DOM.quizTitle().classList.remove("hide");
+  DOM.quizQuestionContainer().classList.remove("hide");
+  DOM.quizAnswerContainer().classList.remove("hide");
+}
+
+/** Hides and resets the quiz question and answer, and shows the start button. */
+function hideQuizView() {
+  DOM.quizTitle().classList.add("hide");
+  DOM.quizTitle().innerText = "Quiz";
+  DOM.quizQuestionContainer().classList.add("hide");
+  DOM.quizQuestion().innerText = "";
+  DOM.quizAnswerContainer().classList.add("hide");
+  DOM.quizAnswer().innerText = "";
+  DOM.quizSubmitForm().classList.add("hide");
+  DOM.quizAnswerInput().value = "";
+  DOM.quizAnswerStatus().innerText = "";
+  openQuestionID = undefined;
+  showQuizMedia(undefined);
+  DOM.startQuizButton().classList.remove("hide");
+}
+
+/**
+ * Parses the given payload as a JSON quiz message.
+ * Returns undefined if the payload is plain text, as sent by servers in compatibility mode,
+ * or if the message version is unsupported.
+ * @param {string} payload
+ * @returns {quiz.ReceivableMessage | undefined}
+ */
+function parseQuizMessage(payload) {
+  let message;
+  try {
+    message = JSON.parse(payload);
+  } catch {
+    return undefined;
+  }
+
+  if (typeof message !== "object" || message === null) {
+    return undefined;
+  }
+
+  if (message.version !== MQTT_MESSAGE_VERSION) {
+    console.log("Unsupported MQTT message version:", message.version);
+    return undefined;
+  }
+
+  return message;
+}
+
 /** Starts a new quiz session by publishing a start quiz message to the MQTT broker. */
 export function startQuiz() {
   if (!mqttClient) {
@@ -108,11 +318,58 @@ export function startQuiz() {
     return;
   }
 
-  const message = new Paho.MQTT.Message(mqttMessages.START);
+  /** @type {quiz.StartQuizMessage} */
+  const startMessage = { version: MQTT_MESSAGE_VERSION, type: mqttMessages.START };
+
+  const message = new Paho.MQTT.Message(JSON.stringify(startMessage));
   message.destinationName = mqttTopics.STATUS;
   mqttClient.send(message);
 }
 
+/**
+ * Submits the answer in the answer input to the question currently open for answers, by publishing
+ * it to the MQTT broker. The server acknowledges or rejects it on the client's reply topic.
+ */
+export function submitAnswer() {
+  const answer = DOM.quizAnswerInput().value.trim();
+  if (openQuestionID === undefined || answer === "") {
+    return;
+  }
+
+  const user = getUsername();
+
+  /** @type {quiz.SubmitAnswerMessage} */
+  const submitMessage = {
+    version: MQTT_MESSAGE_VERSION,
+    type: mqttMessages.SUBMIT_ANSWER,
+    questionId: openQuestionID,
+    answer,
+    name: user.ok ? user.name : mqttClientID,
+  };
+  if (publishMQTT(mqttTopics.SUBMISSIONS, JSON.stringify(submitMessage))) {
+    DOM.quizAnswerStatus().innerText = "Answer sent...";
+  }
+}
+
+/**
+ * Publishes the given payload to the given topic on the MQTT broker.
+ * Returns false if the MQTT client is not connected.
+ * @param {string} topic
+ * @param {string} payload
+ * @returns {boolean}
+ */
+export function publishMQTT(topic, payload) {
+  if (!mqttClient?.isConnected()) {
+    console.log(`Failed to publish to '${topic}': MQTT client not connected.`);
+    return false;
+  }
+
+  const message = new Paho.MQTT.Message(payload);
+  message.destinationName = topic;
+  mqttClient.send(message);
+  return true;
+}
+
 /** Disconnects from the MQTT broker. */
 export function disconnectMQTT() {
   if (mqttClient?.isConnected()) {
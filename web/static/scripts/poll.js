@@ -0,0 +1,227 @@
+import { DOM } from "./dom.js";
+import { mqttClientID, publishMQTT } from "./mqtt.js";
+
+/** Shared prefix for MQTT poll topics. */
+const POLL_TOPIC_PREFIX = "coffeetalk/polls";
+
+/**
+ * MQTT poll topics to listen and post on.
+ * Should be updated if the poll server topic configuration changes.
+ */
+const pollTopics = {
+  STATUS: `${POLL_TOPIC_PREFIX}/status`,
+  VOTES: `${POLL_TOPIC_PREFIX}/votes`,
+  RESULTS: `${POLL_TOPIC_PREFIX}/results`,
+  /** Prefix of the topics where the server replies to individual clients, by client ID. */
+  REPLIES: `${POLL_TOPIC_PREFIX}/replies/`,
+};
+
+/**
+ * MQTT poll message types that the server expects.
+ * Should be updated if the poll server message configuration changes.
+ * @type {poll.MessageTypes}
+ */
+const pollMessages = {
+  START: "start-poll",
+  CLOSE: "close-poll",
+  VOTE: "vote",
+  POLL: "poll",
+  ERROR: "error",
+};
+
+/** Version of the poll server's JSON message format that this client understands. */
+const POLL_MESSAGE_VERSION = 1;
+
+/**
+ * The latest poll published by the server.
+ * Undefined if there is none.
+ * @type {poll.PollMessage | undefined}
+ */
+let currentPoll;
+
+/**
+ * Index of the option this client voted for in the current poll.
+ * Undefined if the client has not voted.
+ * @type {number | undefined}
+ */
+let votedOption;
+
+/**
+ * Returns the poll topics for the client to subscribe to: the poll results, and the client's own
+ * reply topic.
+ * @returns {string[]}
+ */
+export function pollSubscriptions() {
+  return [pollTopics.RESULTS, `${pollTopics.REPLIES}${mqttClientID}`];
+}
+
+/**
+ * Returns whether the given MQTT topic is a poll topic.
+ * @param {string} topic
+ * @returns {boolean}
+ */
+export function isPollTopic(topic) {
+  return topic.startsWith(`${POLL_TOPIC_PREFIX}/`);
+}
+
+/**
+ * Handles the given MQTT message payload from the given poll topic.
+ * @param {string} topic
+ * @param {string} payload
+ */
+export function handlePollMessage(topic, payload) {
+  // The server clears the retained poll results with an empty payload.
+  if (payload === "") {
+    if (topic === pollTopics.RESULTS) {
+      hidePoll();
+    }
+    return;
+  }
+
+  const message = parsePollMessage(payload);
+
+  switch (topic) {
+    case pollTopics.RESULTS:
+      if (message?.type === pollMessages.POLL) {
+        showPoll(message);
+      }
+      break;
+    case `${pollTopics.REPLIES}${mqttClientID}`:
+      if (message?.type === pollMessages.ERROR) {
+        console.log(`Poll server rejected '${message.request}':`, message.error);
+      }
+      break;
+    default:
+      console.log("Unrecognized MQTT poll topic:", topic);
+  }
+}
+
+/**
+ * Shows the given poll with its tally, with a vote button for each option while it is open.
+ * @param {poll.PollMessage} poll
+ */
+function showPoll(poll) {
+  if (poll.pollId !== currentPoll?.pollId) {
+    votedOption = undefined;
+  }
+  currentPoll = poll;
+
+  const closedSuffix = poll.open ? "" : " (closed)";
+  DOM.pollTitle().innerText = `Poll: ${poll.question} - ${poll.votes} votes${closedSuffix}`;
+
+  const options = poll.options.map((tally, index) => {
+    const button = document.createElement("button");
+    const votedSuffix = index === votedOption ? " - your vote" : "";
+    button.innerText = `${tally.option} (${tally.votes})${votedSuffix}`;
+    button.disabled = !poll.open;
+    button.addEventListener("click", () => vote(index));
+    return button;
+  });
+  DOM.pollOptions().replaceChildren(...options);
+
+  DOM.closePollButton().classList.toggle("hide", !poll.open || poll.creator !== mqttClientID);
+  DOM.pollForm().classList.toggle("hide", poll.open);
+  DOM.pollView().classList.remove("hide");
+}
+
+/** Hides and resets the poll view, and shows the form for starting a poll. */
+function hidePoll() {
+  currentPoll = undefined;
+  votedOption = undefined;
+  DOM.pollView().classList.add("hide");
+  DOM.pollTitle().innerText = "";
+  DOM.pollOptions().replaceChildren();
+  DOM.pollForm().classList.remove("hide");
+}
+
+/**
+ * Parses the given payload as a JSON poll message.
+ * Returns undefined if the payload is not JSON, or if the message version is unsupported.
+ * @param {string} payload
+ * @returns {poll.ReceivableMessage | undefined}
+ */
+function parsePollMessage(payload) {
+  let message;
+  try {
+    message = JSON.parse(payload);
+  } catch {
+    return undefined;
+  }
+
+  if (typeof message !== "object" || message === null) {
+    return undefined;
+  }
+
+  if (message.version !== POLL_MESSAGE_VERSION) {
+    console.log("Unsupported MQTT poll message version:", message.version);
+    return undefined;
+  }
+
+  return message;
+}
+
+/**
+ * Starts a new poll with the question and comma-separated options from the poll form, by
+ * publishing a start poll message to the MQTT broker.
+ */
+export function startPoll() {
+  const question = DOM.pollQuestionInput().value.trim();
+  const options = DOM.pollOptionsInput()
+    .value.split(",")
+    .map((option) => option.trim())
+    .filter((option) => option !== "");
+
+  if (question === "" || options.length < 2) {
+    console.log("Failed to start poll: a question and at least 2 options are required.");
+    return;
+  }
+
+  /** @type {poll.StartPollMessage} */
+  const startMessage = {
+    version: POLL_MESSAGE_VERSION,
+    type: pollMessages.START,
+    question,
+    options,
+  };
+  if (publishMQTT(pollTopics.STATUS, JSON.stringify(startMessage))) {
+    DOM.pollQuestionInput().value = "";
+    DOM.pollOptionsInput().value = "";
+  }
+}
+
+/** Closes the current poll before it times out, if this client started it. */
+export function closePoll() {
+  if (!currentPoll) {
+    return;
+  }
+
+  /** @type {poll.ClosePollMessage} */
+  const closeMessage = {
+    version: POLL_MESSAGE_VERSION,
+    type: pollMessages.CLOSE,
+    pollId: currentPoll.pollId,
+  };
+  publishMQTT(pollTopics.STATUS, JSON.stringify(closeMessage));
+}
+
+/**
+ * Votes for the option with the given index in the current poll, replacing any previous vote.
+ * @param {number} option
+ */
+function vote(option) {
+  if (!currentPoll?.open) {
+    return;
+  }
+
+  /** @type {poll.VoteMessage} */
+  const voteMessage = {
+    version: POLL_MESSAGE_VERSION,
+    type: pollMessages.VOTE,
+    pollId: currentPoll.pollId,
+    option,
+  };
+  if (publishMQTT(pollTopics.VOTES, JSON.stringify(voteMessage))) {
+    votedOption = option;
+    showPoll(currentPoll);
+  }
+}
@@ -2,4 +2,10 @@ module github.com/dcs-team4/coffeetalk/webrtc
 
 go 1.18
 
-require github.com/gorilla/websocket v1.5.0
+require (
+	github.com/dcs-team4/coffeetalk/tlscert v0.0.0
+	github.com/gorilla/websocket v1.5.0
+)
+
+// Shared module in this repository, not published separately.
+replace github.com/dcs-team4/coffeetalk/tlscert => ../tlscert